@@ -7,5 +7,5 @@ type Bot interface {
 	Finalize() error
 	Connect(...string) error
 	Disconnect()
-	Reconnect()
+	Reconnect() error
 }
@@ -3,7 +3,8 @@ package bot
 import (
 	"encoding/json"
 	"fmt"
-	"reflect"
+	"math"
+	"sort"
 	"strings"
 	"time"
 
@@ -24,6 +25,25 @@ func valueFromUtil(fName string) (interface{}, error) {
 	}
 }
 
+// scope is the storage seen by the operations of a bot: its storage plus
+// the variables the bot sets while running, such as $loop.index, which are
+// never written to the storage
+type scope struct {
+	storage.Storage
+	variables map[string]interface{}
+}
+
+// lookupVariable returns the value of a bot variable, if store is a scope
+// where it is set
+func lookupVariable(store storage.Storage, name string) (interface{}, error) {
+	if s, ok := store.(*scope); ok {
+		if value, ok := s.variables[name]; ok {
+			return value, nil
+		}
+	}
+	return nil, constants.ErrStorageKeyNotFound
+}
+
 func tryGetValue(expr interface{}, store storage.Storage) (interface{}, error) {
 	if val, ok := expr.(string); ok {
 		if strings.HasPrefix(val, "$store") {
@@ -31,6 +51,10 @@ func tryGetValue(expr interface{}, store storage.Storage) (interface{}, error) {
 			return store.Get(variable)
 		}
 
+		if strings.HasPrefix(val, "$loop") || strings.HasPrefix(val, "$push") {
+			return lookupVariable(store, val)
+		}
+
 		if strings.HasPrefix(val, "$util") {
 			f := val[6:]
 			return valueFromUtil(f)
@@ -40,17 +64,46 @@ func tryGetValue(expr interface{}, store storage.Storage) (interface{}, error) {
 	return nil, nil
 }
 
+var allowedTypes = map[string]bool{
+	"string": true,
+	"bool":   true,
+	"int":    true,
+	"float":  true,
+	"object": true,
+	"array":  true,
+	"any":    true,
+	"<nil>":  true,
+}
+
 func assertType(value interface{}, typ string) (interface{}, error) {
-	allowedTypes := map[string]bool{"string": true, "bool": true, "int": true, "<nil>": true}
 	if _, ok := allowedTypes[typ]; !ok {
 		return nil, fmt.Errorf("Unknown type %s", typ)
 	}
 
+	if typ == "any" {
+		return value, nil
+	}
+
 	switch v := value.(type) {
-	case string, bool, int, nil:
+	case string, bool, nil:
 		return assertCastedType(v, fmt.Sprintf("%T", v), typ)
+	case int:
+		if typ == "float" {
+			return float64(v), nil
+		}
+		return assertCastedType(v, "int", typ)
 	case float64:
-		return assertCastedType(int(v), "int", typ)
+		if typ == "float" {
+			return v, nil
+		}
+		if v == math.Trunc(v) {
+			return assertCastedType(int(v), "int", typ)
+		}
+		return assertCastedType(v, "float", typ)
+	case map[string]interface{}:
+		return assertCastedType(v, "object", typ)
+	case []interface{}:
+		return assertCastedType(v, "array", typ)
 	default:
 		return nil, fmt.Errorf("Unknown value type %T", v)
 	}
@@ -80,14 +133,12 @@ func parseArg(params interface{}, store storage.Storage) (interface{}, error) {
 		return nil, fmt.Errorf("type is not a string")
 	}
 
-	var paramValue interface{}
 	if valueFromStorage != nil {
-		paramValue = valueFromStorage
-	} else {
-		paramValue = p["value"]
+		// stored values were already built when they were saved
+		return assertType(valueFromStorage, paramType)
 	}
 
-	builtParam, err := buildArgByType(paramValue, paramType, store)
+	builtParam, err := buildArgByType(p["value"], paramType, store)
 	if err != nil {
 		return nil, err
 	}
@@ -96,6 +147,10 @@ func parseArg(params interface{}, store storage.Storage) (interface{}, error) {
 }
 
 func buildArgByType(value interface{}, valueType string, store storage.Storage) (interface{}, error) {
+	if valueType == "any" {
+		return value, nil
+	}
+
 	switch arg := value.(type) {
 	case map[string]interface{}:
 		return parseObject(arg, valueType, store)
@@ -140,44 +195,84 @@ func parseArray(arg []interface{}, argType string, store storage.Storage) (inter
 	return preparedArgs, nil
 }
 
-func sendRequest(args interface{}, route string, pclient *PClient, metricsReporter []metrics.Reporter, logger logrus.FieldLogger) (Response, []byte, error) {
+func sendRequest(args interface{}, route string, timeout time.Duration, pclient *PClient, metricsReporter []metrics.Reporter, logger logrus.FieldLogger) (Response, []byte, error) {
 	encodedData, err := json.Marshal(args)
 	if err != nil {
 		return nil, nil, err
 	}
 
+	metricsReporterTags := map[string]string{"route": route}
+	metrics.ReportCount(metricsReporter, constants.BytesSent, metricsReporterTags, float64(len(encodedData)), logger)
+
 	startTime := time.Now()
-	response, b, err := pclient.Request(route, encodedData)
+	response, b, err := pclient.Request(route, encodedData, timeout)
+	if err == constants.ErrBotAborted {
+		// the request didn't fail, the bot stopped waiting for it
+		return nil, nil, err
+	}
 	if err != nil {
-		metricsReporterTags := map[string]string{"route": route}
-		for _, mr := range metricsReporter {
-			reportErr := mr.ReportCount(constants.ErrorCount, metricsReporterTags, 1)
-			if reportErr != nil {
-				logger.WithError(reportErr).Error("Failed to Report Count")
-			}
+		metrics.ReportCount(metricsReporter, constants.ErrorCount, metricsReporterTags, 1, logger)
+		if _, ok := err.(*TimeoutError); ok {
+			metrics.ReportCount(metricsReporter, constants.RequestTimeoutCount, metricsReporterTags, 1, logger)
 		}
+	} else {
+		metrics.ReportCount(metricsReporter, constants.BytesReceived, metricsReporterTags, float64(len(b)), logger)
 	}
 
 	elapsed := time.Since(startTime)
+	reportLatency(metricsReporter, constants.ResponseTime, constants.ResponseTimeHistogram, metricsReporterTags, elapsed, logger)
 
-	metricsReporterTags := map[string]string{"route": route}
-	for _, mr := range metricsReporter {
-		reportErr := mr.ReportSummary(constants.ResponseTime, metricsReporterTags, float64(elapsed.Nanoseconds()/1e6))
-		if reportErr != nil {
-			logger.WithError(reportErr).Error("Failed to Report Summary")
-		}
+	return response, b, err
+}
+
+// receivePush waits for a push on any of the routes, reporting how long it
+// took. When it times out, the wait is reported for all routes joined
+func receivePush(routes []string, timeout int, pclient *PClient, metricsReporter []metrics.Reporter, logger logrus.FieldLogger) (*Push, Response, error) {
+	startTime := time.Now()
+	push, response, err := pclient.ReceivePush(routes, timeout)
+	elapsed := time.Since(startTime)
+	if err == constants.ErrBotAborted {
+		return nil, nil, err
 	}
 
-	return response, b, err
+	route := strings.Join(routes, ",")
+	if _, ok := err.(*TimeoutError); ok {
+		metrics.ReportCount(metricsReporter, constants.PushTimeoutCount, map[string]string{"route": route}, 1, logger)
+	} else if err != nil {
+		metrics.ReportCount(metricsReporter, constants.ErrorCount, map[string]string{"route": route}, 1, logger)
+	} else {
+		route = push.Route
+		metrics.ReportCount(metricsReporter, constants.BytesReceived, map[string]string{"route": route}, float64(len(push.Data)), logger)
+	}
+
+	reportLatency(metricsReporter, constants.PushWaitTime, constants.PushWaitTimeHistogram, map[string]string{"route": route}, elapsed, logger)
+
+	return push, response, err
 }
 
-func sendNotify(args interface{}, route string, pclient *PClient) error {
+// reportLatency reports elapsed in milliseconds both as a summary and as a
+// histogram
+func reportLatency(metricsReporter []metrics.Reporter, summary, histogram string, tags map[string]string, elapsed time.Duration, logger logrus.FieldLogger) {
+	value := float64(elapsed) / float64(time.Millisecond)
+	metrics.ReportSummary(metricsReporter, summary, tags, value, logger)
+	metrics.ReportHistogram(metricsReporter, histogram, tags, value, logger)
+}
+
+func sendNotify(args interface{}, route string, pclient *PClient, metricsReporter []metrics.Reporter, logger logrus.FieldLogger) error {
 	encodedData, err := json.Marshal(args)
 	if err != nil {
 		return err
 	}
 
-	return pclient.Notify(route, encodedData)
+	metricsReporterTags := map[string]string{"route": route}
+	metrics.ReportCount(metricsReporter, constants.NotifyCount, metricsReporterTags, 1, logger)
+	metrics.ReportCount(metricsReporter, constants.BytesSent, metricsReporterTags, float64(len(encodedData)), logger)
+	err = pclient.Notify(route, encodedData)
+	if err != nil {
+		metrics.ReportCount(metricsReporter, constants.NotifyErrorCount, metricsReporterTags, 1, logger)
+	}
+
+	return err
 }
 
 func getValueFromSpec(spec models.ExpectSpecEntry, store storage.Storage) (interface{}, error) {
@@ -196,62 +291,136 @@ func getValueFromSpec(spec models.ExpectSpecEntry, store storage.Storage) (inter
 	return value, nil
 }
 
-func validateExpectations(expectations models.ExpectSpec, response Response, store storage.Storage) error {
-	for propertyExpr, spec := range expectations {
-		expectedValue, err := getValueFromSpec(spec, store)
-		if err != nil {
-			return err
-		}
+// mergeExpect returns the entries of both expectations, the ones in override
+// replacing the ones in base with the same expression
+func mergeExpect(base, override models.ExpectSpec) models.ExpectSpec {
+	ret := make(models.ExpectSpec, len(base)+len(override))
+	for expr, entry := range base {
+		ret[expr] = entry
+	}
+	for expr, entry := range override {
+		ret[expr] = entry
+	}
+	return ret
+}
 
-		gotValue, err := tryExtractValue(&response, Expr(propertyExpr), spec.Type)
-		if err != nil {
-			return err
-		}
+// mergeStore returns the entries of both store specs, the ones in override
+// replacing the ones in base with the same name
+func mergeStore(base, override models.StoreSpec) models.StoreSpec {
+	ret := make(models.StoreSpec, len(base)+len(override))
+	for name, entry := range base {
+		ret[name] = entry
+	}
+	for name, entry := range override {
+		ret[name] = entry
+	}
+	return ret
+}
+
+// validateExpectations returns an *ExpectationError for the first entry that
+// fails, in the order of their expressions
+func validateExpectations(expectations models.ExpectSpec, response Response, store storage.Storage) error {
+	exprs := make([]string, 0, len(expectations))
+	for propertyExpr := range expectations {
+		exprs = append(exprs, propertyExpr)
+	}
+	sort.Strings(exprs)
 
-		if !equals(expectedValue, gotValue) {
-			return fmt.Errorf("%v != %v", expectedValue, gotValue)
+	for _, propertyExpr := range exprs {
+		spec := expectations[propertyExpr]
+		if err := validateExpectation(Expr(propertyExpr), spec, response, store); err != nil {
+			return &ExpectationError{Expr: propertyExpr, Operator: spec.GetOperator(), Err: err}
 		}
 	}
 
 	return nil
 }
 
+// compareOptions relaxes the structural comparison of objects and arrays
+type compareOptions struct {
+	ignoreOrder       bool
+	ignoreExtraFields bool
+}
+
 func equals(lhs interface{}, rhs interface{}) bool {
-	t := reflect.TypeOf(lhs)
+	return deepEquals(lhs, rhs, compareOptions{})
+}
 
-	switch t.Kind() {
-	case reflect.String:
-		lhsVal := lhs.(string)
-		rhsVal, err := assertType(rhs, "string")
-		if err != nil {
+// deepEquals compares the expected value lhs with the received value rhs.
+// Numbers are compared by value, objects and arrays are compared recursively
+func deepEquals(lhs interface{}, rhs interface{}, opts compareOptions) bool {
+	switch lhsVal := lhs.(type) {
+	case nil:
+		return rhs == nil
+	case string:
+		rhsVal, ok := rhs.(string)
+		return ok && lhsVal == rhsVal
+	case bool:
+		rhsVal, ok := rhs.(bool)
+		return ok && lhsVal == rhsVal
+	case int, float64:
+		lhsNum, _ := toFloat(lhs)
+		rhsNum, ok := toFloat(rhs)
+		return ok && lhsNum == rhsNum
+	case map[string]interface{}:
+		rhsVal, ok := rhs.(map[string]interface{})
+		if !ok {
 			return false
 		}
-
-		return lhsVal == rhsVal
-	case reflect.Int:
-		lhsVal := lhs.(int)
-		rhsVal, err := assertType(rhs, "int")
-		if err != nil {
+		if !opts.ignoreExtraFields && len(lhsVal) != len(rhsVal) {
 			return false
 		}
-
-		return lhsVal == rhsVal
-
-	case reflect.Bool:
-		lhsVal := lhs.(bool)
-		rhsVal, err := assertType(rhs, "bool")
-		if err != nil {
+		for key, value := range lhsVal {
+			other, ok := rhsVal[key]
+			if !ok || !deepEquals(value, other, opts) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		rhsVal, ok := rhs.([]interface{})
+		if !ok || len(lhsVal) != len(rhsVal) {
 			return false
 		}
-
-		return lhsVal == rhsVal
-
+		if !opts.ignoreOrder {
+			for i := range lhsVal {
+				if !deepEquals(lhsVal[i], rhsVal[i], opts) {
+					return false
+				}
+			}
+			return true
+		}
+		matched := make([]bool, len(rhsVal))
+		for _, value := range lhsVal {
+			found := false
+			for i, other := range rhsVal {
+				if !matched[i] && deepEquals(value, other, opts) {
+					matched[i] = true
+					found = true
+					break
+				}
+			}
+			if !found {
+				return false
+			}
+		}
+		return true
 	default:
-		fmt.Printf("Unknown type %s\n", t.Kind().String())
 		return false
 	}
 }
 
+func toFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case int:
+		return float64(v), true
+	case float64:
+		return v, true
+	default:
+		return 0, false
+	}
+}
+
 func storeData(
 	storeSpec models.StoreSpec,
 	store storage.Storage,
@@ -4,8 +4,12 @@ import (
 	"errors"
 	"regexp"
 	"testing"
+	"time"
 
+	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
+	"github.com/topfreegames/pitaya-bot/constants"
+	"github.com/topfreegames/pitaya-bot/metrics"
 	"github.com/topfreegames/pitaya-bot/models"
 	"github.com/topfreegames/pitaya-bot/storage"
 )
@@ -25,6 +29,14 @@ func TestAssertType(t *testing.T) {
 		"err_string":        {1, "string", nil, errors.New("string type assertion failed for field: 1")},
 		"err_unknown_type":  {"5", "rand", nil, errors.New("Unknown type rand")},
 		"err_string_to_int": {"6", "int", nil, errors.New("int type assertion failed for field: 6")},
+		"success_float":     {1.5, "float", 1.5, nil},
+		"success_int_float": {2, "float", float64(2), nil},
+		"success_json_int":  {float64(3), "int", 3, nil},
+		"err_float_to_int":  {1.5, "int", nil, errors.New("int type assertion failed for field: 1.5")},
+		"success_object":    {map[string]interface{}{"a": "b"}, "object", map[string]interface{}{"a": "b"}, nil},
+		"success_array":     {[]interface{}{"a"}, "array", []interface{}{"a"}, nil},
+		"err_array":         {[]interface{}{"a"}, "object", nil, errors.New("object type assertion failed for field: [a]")},
+		"success_any":       {map[string]interface{}{"a": 1.5}, "any", map[string]interface{}{"a": 1.5}, nil},
 	}
 
 	for name, table := range assertTypeTable {
@@ -51,6 +63,11 @@ func TestBuildArgsByType(t *testing.T) {
 		}, "object", &storage.MemoryStorage{"playerId": "123456"}, map[string]interface{}{"playerId": "123456", "gold": 10}, nil},
 		"error_one":            {map[string]interface{}{"playerId": map[string]interface{}{"type": "string", "value": "$store.playerId2"}}, "object", &storage.MemoryStorage{"playerId": "123456"}, nil, errors.New("storage key not found")},
 		"error_undefined_util": {map[string]interface{}{"playerId": map[string]interface{}{"type": "string", "value": "$util.unknown"}}, "object", nil, nil, errors.New("util.unknown undefined")},
+		"success_float":        {map[string]interface{}{"price": map[string]interface{}{"type": "float", "value": 9.99}}, "object", nil, map[string]interface{}{"price": 9.99}, nil},
+		"success_stored_object": {map[string]interface{}{"player": map[string]interface{}{"type": "object", "value": "$store.player"}}, "object", &storage.MemoryStorage{"player": map[string]interface{}{"id": "123456"}},
+			map[string]interface{}{"player": map[string]interface{}{"id": "123456"}}, nil},
+		"success_any": {map[string]interface{}{"raw": map[string]interface{}{"type": "any", "value": map[string]interface{}{"ids": []interface{}{1.0, 2.0}}}}, "object", nil,
+			map[string]interface{}{"raw": map[string]interface{}{"ids": []interface{}{1.0, 2.0}}}, nil},
 	}
 
 	for name, table := range buildArgsWithStorageTable {
@@ -88,6 +105,14 @@ func TestEquals(t *testing.T) {
 		"bool_false2":    {true, 1, false},
 		"unknown_false1": {map[string]bool{}, "int", false},
 		"unknown_false2": {"int", map[string]bool{}, false},
+		"float_true":     {1.5, 1.5, true},
+		"float_int_true": {2, float64(2), true},
+		"float_false":    {1, 1.5, false},
+		"object_true":    {map[string]interface{}{"a": 1, "b": []interface{}{"c"}}, map[string]interface{}{"b": []interface{}{"c"}, "a": float64(1)}, true},
+		"object_false":   {map[string]interface{}{"a": 1}, map[string]interface{}{"a": 1.0, "b": 2.0}, false},
+		"array_true":     {[]interface{}{"a", "b"}, []interface{}{"a", "b"}, true},
+		"array_false":    {[]interface{}{"a", "b"}, []interface{}{"b", "a"}, false},
+		"nil_true":       {nil, nil, true},
 	}
 
 	for name, table := range equalsTable {
@@ -98,6 +123,28 @@ func TestEquals(t *testing.T) {
 	}
 }
 
+func TestDeepEqualsOptions(t *testing.T) {
+	var deepEqualsTable = map[string]struct {
+		value1 interface{}
+		value2 interface{}
+		opts   compareOptions
+		result bool
+	}{
+		"ignore_order":             {[]interface{}{"a", "b", "a"}, []interface{}{"b", "a", "a"}, compareOptions{ignoreOrder: true}, true},
+		"ignore_order_false":       {[]interface{}{"a", "b", "a"}, []interface{}{"b", "b", "a"}, compareOptions{ignoreOrder: true}, false},
+		"ignore_extra_fields":      {map[string]interface{}{"a": 1}, map[string]interface{}{"a": 1.0, "b": 2.0}, compareOptions{ignoreExtraFields: true}, true},
+		"ignore_extra_fields_deep": {map[string]interface{}{"a": map[string]interface{}{"b": 1}}, map[string]interface{}{"a": map[string]interface{}{"b": 1.0, "c": 2.0}}, compareOptions{ignoreExtraFields: true}, true},
+		"ignore_extra_fields_miss": {map[string]interface{}{"a": 1, "c": 3}, map[string]interface{}{"a": 1.0, "b": 2.0}, compareOptions{ignoreExtraFields: true}, false},
+	}
+
+	for name, table := range deepEqualsTable {
+		t.Run(name, func(t *testing.T) {
+			val := deepEquals(table.value1, table.value2, table.opts)
+			assert.Equal(t, table.result, val)
+		})
+	}
+}
+
 func TestStoreData(t *testing.T) {
 	var equalsTable = map[string]struct {
 		storeSpec models.StoreSpec
@@ -108,7 +155,7 @@ func TestStoreData(t *testing.T) {
 		"store_value_success": {models.StoreSpec{"storeVal": models.StoreSpecEntry{Type: "string", Value: "val"}}, &storage.MemoryStorage{}, map[string]interface{}{"val": "value"}, nil},
 		"store_map_success":   {models.StoreSpec{"storeVal": models.StoreSpecEntry{Type: "string", Value: "$response.val[\"valMap\"]"}}, &storage.MemoryStorage{}, map[string]interface{}{"val": map[string]interface{}{"valMap": "value"}}, nil},
 		"store_slice_success": {models.StoreSpec{"storeVal": models.StoreSpecEntry{Type: "string", Value: "$response.val[0]"}}, &storage.MemoryStorage{}, map[string]interface{}{"val": []interface{}{"value"}}, nil},
-		"store_error_token":   {models.StoreSpec{"storeVal": models.StoreSpecEntry{Type: "string", Value: "val"}}, &storage.MemoryStorage{}, nil, errors.New("string type assertion failed for field: <nil>")},
+		"store_error_token":   {models.StoreSpec{"storeVal": models.StoreSpecEntry{Type: "string", Value: "val"}}, &storage.MemoryStorage{}, nil, errors.New("malformed spec file. expr val doesn't match the object received")},
 		"store_error_type":    {models.StoreSpec{"storeVal": models.StoreSpecEntry{Type: "string", Value: "val"}}, &storage.MemoryStorage{}, map[string]interface{}{"val": 1}, errors.New("string type assertion failed for field: 1")},
 	}
 
@@ -124,3 +171,61 @@ func TestStoreData(t *testing.T) {
 		})
 	}
 }
+
+func TestStoreDataTypes(t *testing.T) {
+	response := map[string]interface{}{
+		"price":  9.99,
+		"player": map[string]interface{}{"id": "123456"},
+		"items":  []interface{}{"sword"},
+	}
+	storeSpec := models.StoreSpec{
+		"price":  models.StoreSpecEntry{Type: "float", Value: "$response.price"},
+		"player": models.StoreSpecEntry{Type: "object", Value: "$response.player"},
+		"items":  models.StoreSpecEntry{Type: "array", Value: "$response.items"},
+	}
+	store := &storage.MemoryStorage{}
+
+	err := storeData(storeSpec, store, response)
+	assert.NoError(t, err)
+	assert.Equal(t, &storage.MemoryStorage{
+		"price":  9.99,
+		"player": map[string]interface{}{"id": "123456"},
+		"items":  []interface{}{"sword"},
+	}, store)
+}
+
+func TestReportLatencySubMillisecond(t *testing.T) {
+	reporter := newTestReporter()
+	tags := map[string]string{"route": "room.ping"}
+	reportLatency([]metrics.Reporter{reporter}, constants.ResponseTime, constants.ResponseTimeHistogram, tags, 1500*time.Microsecond, logrus.New())
+
+	assert.Equal(t, 1.5, reporter.values[constants.ResponseTime+":room.ping"])
+	assert.Equal(t, 1.5, reporter.values[constants.ResponseTimeHistogram+":room.ping"])
+}
+
+func TestTryGetValueVariables(t *testing.T) {
+	store := &scope{
+		Storage:   &storage.MemoryStorage{"$push.route": "stored.route"},
+		variables: map[string]interface{}{"$loop.index": 2},
+	}
+
+	value, err := tryGetValue("$loop.index", store)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, value)
+
+	_, err = tryGetValue("$push.route", store)
+	assert.Equal(t, constants.ErrStorageKeyNotFound, err)
+}
+
+func TestValidateExpectationsFirstFailure(t *testing.T) {
+	expect := models.ExpectSpec{
+		"$response.gold": {Type: "int", Value: 100, Operator: "gte"},
+		"$response.code": {Type: "string", Value: "200"},
+	}
+	response := map[string]interface{}{"code": "500", "gold": float64(50)}
+
+	err := validateExpectations(expect, response, &storage.MemoryStorage{})
+	assert.Equal(t, &ExpectationError{
+		Expr: "$response.code", Operator: "eq", Err: errors.New("$response.code: 500 eq 200 failed"),
+	}, err)
+}
@@ -0,0 +1,267 @@
+package bot
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/topfreegames/pitaya-bot/models"
+	"github.com/topfreegames/pitaya-bot/storage"
+)
+
+// comparator checks a value received from the server against the expected one
+type comparator struct {
+	// typed is true when the received value must be asserted to the
+	// expectation type before being compared
+	typed   bool
+	compare func(expected, got interface{}, opts compareOptions) (bool, error)
+}
+
+var comparators = map[string]comparator{
+	models.OperatorEqual:          {true, compareEqual},
+	models.OperatorNotEqual:       {true, compareNotEqual},
+	models.OperatorGreater:        {true, compareOrdered(func(c int) bool { return c > 0 })},
+	models.OperatorGreaterOrEqual: {true, compareOrdered(func(c int) bool { return c >= 0 })},
+	models.OperatorLess:           {true, compareOrdered(func(c int) bool { return c < 0 })},
+	models.OperatorLessOrEqual:    {true, compareOrdered(func(c int) bool { return c <= 0 })},
+	models.OperatorRegex:          {true, compareRegex},
+	models.OperatorContains:       {false, compareContains},
+	models.OperatorIn:             {true, compareIn},
+	models.OperatorLen:            {false, compareLen},
+}
+
+func compareEqual(expected, got interface{}, opts compareOptions) (bool, error) {
+	return deepEquals(expected, got, opts), nil
+}
+
+func compareNotEqual(expected, got interface{}, opts compareOptions) (bool, error) {
+	return !deepEquals(expected, got, opts), nil
+}
+
+// compareOrdered returns a comparator that checks the result of comparing
+// the received value with the expected one
+func compareOrdered(check func(int) bool) func(expected, got interface{}, opts compareOptions) (bool, error) {
+	return func(expected, got interface{}, opts compareOptions) (bool, error) {
+		switch e := expected.(type) {
+		case int, float64:
+			expectedNum, _ := toFloat(e)
+			gotNum, ok := toFloat(got)
+			if !ok {
+				return false, fmt.Errorf("cannot compare %v with %v", got, expected)
+			}
+			switch {
+			case gotNum > expectedNum:
+				return check(1), nil
+			case gotNum < expectedNum:
+				return check(-1), nil
+			default:
+				return check(0), nil
+			}
+		case string:
+			g, ok := got.(string)
+			if !ok {
+				return false, fmt.Errorf("cannot compare %v with %v", got, expected)
+			}
+			return check(strings.Compare(g, e)), nil
+		default:
+			return false, fmt.Errorf("type %T is not ordered", expected)
+		}
+	}
+}
+
+func compareRegex(expected, got interface{}, opts compareOptions) (bool, error) {
+	pattern, ok := expected.(string)
+	if !ok {
+		return false, fmt.Errorf("regex pattern must be a string, got %v", expected)
+	}
+	g, ok := got.(string)
+	if !ok {
+		return false, fmt.Errorf("regex can only match strings, got %v", got)
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return false, err
+	}
+
+	return re.MatchString(g), nil
+}
+
+func compareContains(expected, got interface{}, opts compareOptions) (bool, error) {
+	switch g := got.(type) {
+	case string:
+		e, ok := expected.(string)
+		if !ok {
+			return false, fmt.Errorf("string can only contain strings, got %v", expected)
+		}
+		return strings.Contains(g, e), nil
+	case []interface{}:
+		for _, elem := range g {
+			if deepEquals(expected, elem, opts) {
+				return true, nil
+			}
+		}
+		return false, nil
+	case map[string]interface{}:
+		e, ok := expected.(string)
+		if !ok {
+			return false, fmt.Errorf("object keys must be strings, got %v", expected)
+		}
+		_, ok = g[e]
+		return ok, nil
+	default:
+		return false, fmt.Errorf("contains is not supported for value %v", got)
+	}
+}
+
+func compareIn(expected, got interface{}, opts compareOptions) (bool, error) {
+	list, ok := expected.([]interface{})
+	if !ok {
+		return false, fmt.Errorf("in operator expects an array, got %v", expected)
+	}
+
+	for _, elem := range list {
+		if deepEquals(elem, got, opts) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func compareLen(expected, got interface{}, opts compareOptions) (bool, error) {
+	var length int
+	switch g := got.(type) {
+	case string:
+		length = len(g)
+	case []interface{}:
+		length = len(g)
+	case map[string]interface{}:
+		length = len(g)
+	default:
+		return false, fmt.Errorf("len is not supported for value %v", got)
+	}
+
+	return equals(expected, length), nil
+}
+
+// getExpectedValue builds the value the operator will compare against
+func getExpectedValue(operator string, spec models.ExpectSpecEntry, store storage.Storage) (interface{}, error) {
+	if operator != models.OperatorIn {
+		return getValueFromSpec(spec, store)
+	}
+
+	value, err := tryGetValue(spec.Value, store)
+	if err != nil {
+		return nil, err
+	}
+	if value == nil {
+		value = spec.Value
+	}
+
+	list, ok := value.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("in operator expects an array, got %v", value)
+	}
+
+	ret := make([]interface{}, len(list))
+	for i, elem := range list {
+		ret[i], err = assertType(elem, spec.Type)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return ret, nil
+}
+
+func validateExpectation(expr Expr, spec models.ExpectSpecEntry, response Response, store storage.Storage) error {
+	operator := spec.GetOperator()
+
+	switch operator {
+	case models.OperatorExists:
+		if _, err := extractValue(&response, expr); err != nil {
+			return fmt.Errorf("%s %s failed: %s", expr, operator, err)
+		}
+		return nil
+	case models.OperatorNotExists:
+		if got, err := extractValue(&response, expr); err == nil {
+			return fmt.Errorf("%s %s failed: got %v", expr, operator, got)
+		}
+		return nil
+	}
+
+	cmp, ok := comparators[operator]
+	if !ok {
+		return fmt.Errorf("Unknown operator %s", operator)
+	}
+
+	expectedValue, err := getExpectedValue(operator, spec, store)
+	if err != nil {
+		return err
+	}
+
+	var gotValue interface{}
+	if cmp.typed {
+		gotValue, err = tryExtractValue(&response, expr, spec.Type)
+	} else {
+		gotValue, err = extractValue(&response, expr)
+	}
+	if err != nil {
+		return err
+	}
+
+	opts := compareOptions{
+		ignoreOrder:       spec.IgnoreOrder,
+		ignoreExtraFields: spec.IgnoreExtraFields,
+	}
+	ok, err = cmp.compare(expectedValue, gotValue, opts)
+	if err != nil {
+		return fmt.Errorf("%s %s failed: %s", expr, operator, err)
+	}
+	if !ok {
+		return fmt.Errorf("%s: %v %s %v failed", expr, gotValue, operator, expectedValue)
+	}
+
+	return nil
+}
+
+// evaluateCondition returns true when every entry of the condition would pass
+// as an expectation. $store expressions are evaluated against the storage and
+// $response expressions against the given response
+func evaluateCondition(condition models.ExpectSpec, response Response, store storage.Storage) bool {
+	for propertyExpr, spec := range condition {
+		target, expr := conditionTarget(Expr(propertyExpr), response, store)
+		if err := validateExpectation(expr, spec, target, store); err != nil {
+			return false
+		}
+	}
+
+	return true
+}
+
+// conditionTarget rewrites $store, $push.route and $loop.index expressions so
+// they can be extracted as if the stored value was part of a response
+func conditionTarget(expr Expr, response Response, store storage.Storage) (Response, Expr) {
+	if expr == pushRouteKey || expr == loopIndexKey {
+		target := map[string]interface{}{}
+		if value, err := lookupVariable(store, string(expr)); err == nil {
+			target["value"] = value
+		}
+		return target, Expr("$response.value")
+	}
+
+	if !strings.HasPrefix(string(expr), "$store") {
+		return response, expr
+	}
+
+	path := string(expr)[len("$store"):]
+	target := map[string]interface{}{}
+	if tokens := tokenSplit(path); len(tokens) > 0 {
+		if value, err := store.Get(tokens[0]); err == nil {
+			target[tokens[0]] = value
+		}
+	}
+
+	return target, Expr("$response" + path)
+}
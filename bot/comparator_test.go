@@ -0,0 +1,121 @@
+package bot
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/topfreegames/pitaya-bot/models"
+	"github.com/topfreegames/pitaya-bot/storage"
+)
+
+func TestValidateExpectation(t *testing.T) {
+	response := map[string]interface{}{
+		"code":  "200",
+		"gold":  float64(150),
+		"price": 9.99,
+		"token": "0123456789abcdef0123456789abcdef",
+		"items": []interface{}{"sword", "shield"},
+		"player": map[string]interface{}{
+			"name": "bot",
+		},
+	}
+
+	var validateExpectationTable = map[string]struct {
+		expr Expr
+		spec models.ExpectSpecEntry
+		err  error
+	}{
+		"eq_default":        {"$response.code", models.ExpectSpecEntry{Type: "string", Value: "200"}, nil},
+		"eq_store":          {"$response.code", models.ExpectSpecEntry{Type: "string", Value: "$store.code"}, nil},
+		"eq_fail":           {"$response.code", models.ExpectSpecEntry{Type: "string", Value: "404"}, errors.New("$response.code: 200 eq 404 failed")},
+		"ne":                {"$response.code", models.ExpectSpecEntry{Type: "string", Value: "404", Operator: "ne"}, nil},
+		"ne_fail":           {"$response.code", models.ExpectSpecEntry{Type: "string", Value: "200", Operator: "ne"}, errors.New("$response.code: 200 ne 200 failed")},
+		"gt":                {"$response.gold", models.ExpectSpecEntry{Type: "int", Value: 100, Operator: "gt"}, nil},
+		"gte":               {"$response.gold", models.ExpectSpecEntry{Type: "int", Value: 150, Operator: "gte"}, nil},
+		"gte_fail":          {"$response.gold", models.ExpectSpecEntry{Type: "int", Value: 200, Operator: "gte"}, errors.New("$response.gold: 150 gte 200 failed")},
+		"lt":                {"$response.gold", models.ExpectSpecEntry{Type: "int", Value: 200, Operator: "lt"}, nil},
+		"lte_fail":          {"$response.gold", models.ExpectSpecEntry{Type: "int", Value: 100, Operator: "lte"}, errors.New("$response.gold: 150 lte 100 failed")},
+		"gt_string":         {"$response.code", models.ExpectSpecEntry{Type: "string", Value: "100", Operator: "gt"}, nil},
+		"regex":             {"$response.token", models.ExpectSpecEntry{Type: "string", Value: "^[a-f0-9]{32}$", Operator: "regex"}, nil},
+		"regex_fail":        {"$response.code", models.ExpectSpecEntry{Type: "string", Value: "^[a-f]+$", Operator: "regex"}, errors.New("$response.code: 200 regex ^[a-f]+$ failed")},
+		"regex_invalid":     {"$response.code", models.ExpectSpecEntry{Type: "string", Value: "(", Operator: "regex"}, errors.New("$response.code regex failed: error parsing regexp: missing closing ): `(`")},
+		"contains_array":    {"$response.items", models.ExpectSpecEntry{Type: "string", Value: "sword", Operator: "contains"}, nil},
+		"contains_string":   {"$response.token", models.ExpectSpecEntry{Type: "string", Value: "abc", Operator: "contains"}, nil},
+		"contains_object":   {"$response.player", models.ExpectSpecEntry{Type: "string", Value: "name", Operator: "contains"}, nil},
+		"contains_fail":     {"$response.items", models.ExpectSpecEntry{Type: "string", Value: "bow", Operator: "contains"}, errors.New("$response.items: [sword shield] contains bow failed")},
+		"contains_invalid":  {"$response.gold", models.ExpectSpecEntry{Type: "string", Value: "1", Operator: "contains"}, errors.New("$response.gold contains failed: contains is not supported for value 150")},
+		"in":                {"$response.code", models.ExpectSpecEntry{Type: "string", Value: []interface{}{"200", "201"}, Operator: "in"}, nil},
+		"in_fail":           {"$response.code", models.ExpectSpecEntry{Type: "string", Value: []interface{}{"404", "500"}, Operator: "in"}, errors.New("$response.code: 200 in [404 500] failed")},
+		"in_not_array":      {"$response.code", models.ExpectSpecEntry{Type: "string", Value: "200", Operator: "in"}, errors.New("in operator expects an array, got 200")},
+		"exists":            {"$response.player.name", models.ExpectSpecEntry{Operator: "exists"}, nil},
+		"exists_fail":       {"$response.player.id", models.ExpectSpecEntry{Operator: "exists"}, errors.New("$response.player.id exists failed: token 'id' not found within expr $response.player.id")},
+		"not_exists":        {"$response.player.id", models.ExpectSpecEntry{Operator: "notExists"}, nil},
+		"not_exists_fail":   {"$response.code", models.ExpectSpecEntry{Operator: "notExists"}, errors.New("$response.code notExists failed: got 200")},
+		"exists_scalar":     {"$response.gold.missing", models.ExpectSpecEntry{Operator: "exists"}, errors.New("$response.gold.missing exists failed: malformed spec file. expr $response.gold.missing doesn't match the object received")},
+		"not_exists_scalar": {"$response.gold.missing", models.ExpectSpecEntry{Operator: "notExists"}, nil},
+		"len":               {"$response.items", models.ExpectSpecEntry{Type: "int", Value: 2, Operator: "len"}, nil},
+		"len_fail":          {"$response.token", models.ExpectSpecEntry{Type: "int", Value: 2, Operator: "len"}, errors.New("$response.token: 0123456789abcdef0123456789abcdef len 2 failed")},
+		"float_eq":          {"$response.price", models.ExpectSpecEntry{Type: "float", Value: 9.99}, nil},
+		"float_lt":          {"$response.price", models.ExpectSpecEntry{Type: "float", Value: 10, Operator: "lt"}, nil},
+		"float_as_int":      {"$response.price", models.ExpectSpecEntry{Type: "int", Value: 9}, errors.New("int type assertion failed for field: 9.99")},
+		"object_eq":         {"$response.player", models.ExpectSpecEntry{Type: "object", Value: map[string]interface{}{"name": "bot"}}, nil},
+		"object_partial":    {"$response", models.ExpectSpecEntry{Type: "object", Value: map[string]interface{}{"code": "200"}, IgnoreExtraFields: true}, nil},
+		"array_unordered":   {"$response.items", models.ExpectSpecEntry{Type: "array", Value: []interface{}{"shield", "sword"}, IgnoreOrder: true}, nil},
+		"array_ordered":     {"$response.items", models.ExpectSpecEntry{Type: "array", Value: []interface{}{"shield", "sword"}}, errors.New("$response.items: [sword shield] eq [shield sword] failed")},
+		"unknown_operator":  {"$response.code", models.ExpectSpecEntry{Type: "string", Value: "200", Operator: "between"}, errors.New("Unknown operator between")},
+	}
+
+	for name, table := range validateExpectationTable {
+		t.Run(name, func(t *testing.T) {
+			store := &storage.MemoryStorage{"code": "200"}
+			err := validateExpectation(table.expr, table.spec, response, store)
+			assert.Equal(t, table.err, err)
+		})
+	}
+}
+
+func TestValidateExpectationNilResponse(t *testing.T) {
+	store := &storage.MemoryStorage{}
+	err := validateExpectation("$response.code", models.ExpectSpecEntry{Operator: "exists"}, nil, store)
+	assert.Error(t, err)
+	err = validateExpectation("$response.code", models.ExpectSpecEntry{Operator: "notExists"}, nil, store)
+	assert.NoError(t, err)
+}
+
+func TestEvaluateCondition(t *testing.T) {
+	response := map[string]interface{}{"code": "404", "status": "searching"}
+	store := &scope{
+		Storage:   &storage.MemoryStorage{"player": map[string]interface{}{"level": 3.0}, "playerId": "123456", "$loop.index": 2},
+		variables: map[string]interface{}{"$push.route": "match.found"},
+	}
+
+	var evaluateConditionTable = map[string]struct {
+		condition models.ExpectSpec
+		result    bool
+	}{
+		"response_true":     {models.ExpectSpec{"$response.code": {Type: "string", Value: "404"}}, true},
+		"response_false":    {models.ExpectSpec{"$response.code": {Type: "string", Value: "200"}}, false},
+		"response_missing":  {models.ExpectSpec{"$response.player": {Type: "string", Value: "200"}}, false},
+		"store_true":        {models.ExpectSpec{"$store.playerId": {Type: "string", Value: "123456"}}, true},
+		"store_nested_true": {models.ExpectSpec{"$store.player.level": {Type: "int", Value: 2, Operator: "gt"}}, true},
+		"store_exists":      {models.ExpectSpec{"$store.accessToken": {Operator: "notExists"}}, true},
+		"push_route_true":   {models.ExpectSpec{"$push.route": {Type: "string", Value: "match.found"}}, true},
+		"push_route_false":  {models.ExpectSpec{"$push.route": {Type: "string", Value: "match.cancelled"}}, false},
+		"loop_index_unset":  {models.ExpectSpec{"$loop.index": {Operator: "exists"}}, false},
+		"all_true": {models.ExpectSpec{
+			"$response.status": {Type: "string", Value: "searching"},
+			"$store.playerId":  {Operator: "exists"},
+		}, true},
+		"one_false": {models.ExpectSpec{
+			"$response.status": {Type: "string", Value: "searching"},
+			"$store.playerId":  {Operator: "notExists"},
+		}, false},
+	}
+
+	for name, table := range evaluateConditionTable {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, table.result, evaluateCondition(table.condition, response, store))
+		})
+	}
+}
@@ -27,3 +27,52 @@ func NewExpectError(err error, rawData []byte, expect models.ExpectSpec) *Expect
 		Expect:  string(bexpect),
 	}
 }
+
+// ExpectationError is the failure of one entry of an expect block, Expr is
+// its expression and Operator its operator
+type ExpectationError struct {
+	Expr     string
+	Operator string
+	Err      error
+}
+
+func (e *ExpectationError) Error() string {
+	return e.Err.Error()
+}
+
+// TimeoutError is returned when the server doesn't answer a request in time,
+// or when Push is true, when no push arrives in time
+type TimeoutError struct {
+	Route string
+	Push  bool
+}
+
+func (e *TimeoutError) Error() string {
+	if e.Push {
+		return fmt.Sprintf("Timeout waiting for push on route %s", e.Route)
+	}
+	return fmt.Sprintf("Timeout waiting for response on route %s", e.Route)
+}
+
+// RestartError is returned when an operation failed and its policy is to run
+// the spec operations again from the first one
+type RestartError struct {
+	Err error
+}
+
+func (e *RestartError) Error() string {
+	return e.Err.Error()
+}
+
+// OperationError is returned when a bot stops because one of its sequential
+// operations failed. Step is the index of the top level operation
+type OperationError struct {
+	Step int
+	Type string
+	URI  string
+	Err  error
+}
+
+func (e *OperationError) Error() string {
+	return e.Err.Error()
+}
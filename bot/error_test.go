@@ -13,4 +13,14 @@ func TestError(t *testing.T) {
 		err := NewExpectError(errors.New("test"), []byte{}, models.ExpectSpec{"$response.code": models.ExpectSpecEntry{Type: "string", Value: "200"}})
 		assert.Equal(t, "\nErr: test \nRawData:  \nExpected: {\"$response.code\":{\"type\":\"string\",\"value\":\"200\"}}\n", err.Error())
 	})
+
+	t.Run("testOperationError", func(t *testing.T) {
+		err := &OperationError{Step: 2, Type: "request", URI: "room.join", Err: &TimeoutError{Route: "room.join"}}
+		assert.Equal(t, "Timeout waiting for response on route room.join", err.Error())
+	})
+
+	t.Run("testPushTimeoutError", func(t *testing.T) {
+		err := &TimeoutError{Route: "room.start", Push: true}
+		assert.Equal(t, "Timeout waiting for push on route room.start", err.Error())
+	})
 }
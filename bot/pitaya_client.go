@@ -8,6 +8,8 @@ import (
 	"time"
 
 	"github.com/sirupsen/logrus"
+	"github.com/topfreegames/pitaya-bot/constants"
+	"github.com/topfreegames/pitaya-bot/metrics"
 	"github.com/topfreegames/pitaya/v2/client"
 	pitayamessage "github.com/topfreegames/pitaya/v2/conn/message"
 	"github.com/topfreegames/pitaya/v2/session"
@@ -24,12 +26,18 @@ type PClient struct {
 	client         client.PitayaClient
 	responsesMutex sync.Mutex
 	responses      map[uint]chan []byte
+	// sending is the number of requests being sent, whose responses may
+	// arrive before their channel exists and are kept in early meanwhile
+	sending int
+	early   map[uint][]byte
 
-	pushesMutex sync.Mutex
-	pushes      map[string]chan []byte
+	pushes *pushQueues
 
-	timeout time.Duration
-	logger  logrus.FieldLogger
+	timeout         time.Duration
+	logger          logrus.FieldLogger
+	metricsReporter []metrics.Reporter
+	// abort is closed when the bot must stop waiting for responses and pushes
+	abort <-chan struct{}
 }
 
 func getProtoInfo(host string, docs string, pushinfo map[string]string, logger logrus.FieldLogger) *client.ProtoBufferInfo {
@@ -71,7 +79,7 @@ func tryConnect(pClient client.PitayaClient, addr string, useTLS bool, logger lo
 }
 
 // NewPClient is the PCLient constructor
-func NewPClient(host string, useTLS bool, handshake *session.HandshakeData, timeout time.Duration, logger logrus.FieldLogger, docs string, pushinfo map[string]string) (*PClient, error) {
+func NewPClient(host string, useTLS bool, handshake *session.HandshakeData, timeout time.Duration, logger logrus.FieldLogger, docs string, pushinfo map[string]string, pushes *pushQueues, mr []metrics.Reporter, abort <-chan struct{}) (*PClient, error) {
 	var pclient client.PitayaClient
 	if docs != "" {
 		protoclient := client.NewProto(docs, logrus.InfoLevel)
@@ -90,16 +98,27 @@ func NewPClient(host string, useTLS bool, handshake *session.HandshakeData, time
 	}
 
 	return &PClient{
-		client:    pclient,
-		responses: make(map[uint]chan []byte),
-		pushes:    make(map[string]chan []byte),
-		timeout:   timeout,
-		logger:    logger,
+		client:          pclient,
+		responses:       make(map[uint]chan []byte),
+		early:           make(map[uint][]byte),
+		pushes:          pushes,
+		timeout:         timeout,
+		logger:          logger,
+		metricsReporter: mr,
+		abort:           abort,
 	}, nil
 }
 
 // Disconnect disconnects the client
 func (c *PClient) Disconnect() {
+	if c.client == nil {
+		// already disconnected
+		return
+	}
+
+	for route, count := range c.pushes.drain() {
+		metrics.ReportCount(c.metricsReporter, constants.PushUnconsumedCount, map[string]string{"route": route}, float64(count), c.logger)
+	}
 	c.client.Disconnect()
 	c.client = nil
 }
@@ -109,14 +128,70 @@ func (c *PClient) Connected() bool {
 	return c.client != nil && c.client.ConnectedStatus()
 }
 
-func (c *PClient) getResponseChannelForID(id uint) chan []byte {
+// sendRequestWithChannel sends the request and creates the channel its
+// response is delivered to. The id is only known once the request is sent,
+// so the lock is released while sending and a response arriving before its
+// channel exists is taken from the early responses
+func (c *PClient) sendRequestWithChannel(route string, data []byte) (uint, chan []byte, error) {
+	c.responsesMutex.Lock()
+	c.sending++
+	c.responsesMutex.Unlock()
+
+	id, err := c.client.SendRequest(route, data)
+
+	c.responsesMutex.Lock()
+	defer c.responsesMutex.Unlock()
+	defer c.doneSending()
+	if err != nil {
+		return 0, nil, err
+	}
+
+	// buffered so delivering the response never waits for the request
+	ch := make(chan []byte, 1)
+	if responseData, ok := c.early[id]; ok {
+		delete(c.early, id)
+		ch <- responseData
+	}
+	c.responses[id] = ch
+	return id, ch, nil
+}
+
+// doneSending drops the early responses no request claimed once no request
+// is being sent, they answer requests that already timed out. It must be
+// called with the lock held
+func (c *PClient) doneSending() {
+	c.sending--
+	if c.sending == 0 {
+		for id := range c.early {
+			delete(c.early, id)
+		}
+	}
+}
+
+// deliverResponse sends the response to the channel of its request. While
+// requests are being sent, responses without a channel are kept as they may
+// answer one of them. It returns false if the response is dropped because
+// its request already timed out or was never sent
+func (c *PClient) deliverResponse(id uint, data []byte) bool {
 	c.responsesMutex.Lock()
 	defer c.responsesMutex.Unlock()
-	if _, ok := c.responses[id]; !ok {
-		c.responses[id] = make(chan []byte)
+
+	ch, ok := c.responses[id]
+	if !ok {
+		if c.sending > 0 {
+			c.early[id] = data
+			return true
+		}
+		return false
 	}
 
-	return c.responses[id]
+	select {
+	case ch <- data:
+		return true
+	default:
+		// the request already has a response
+		return false
+	}
 }
 
 func (c *PClient) removeResponseChannelForID(id uint) {
@@ -126,24 +201,19 @@ func (c *PClient) removeResponseChannelForID(id uint) {
 	delete(c.responses, id)
 }
 
-func (c *PClient) getPushChannelForRoute(route string) chan []byte {
-	c.pushesMutex.Lock()
-	defer c.pushesMutex.Unlock()
-	if _, ok := c.pushes[route]; !ok {
-		c.pushes[route] = make(chan []byte)
+// Request sends a request and waits for its response, timeout defaults to the
+// client timeout when it is not positive. It fails with ErrBotAborted if the
+// bot is aborted while waiting
+func (c *PClient) Request(route string, data []byte, timeout time.Duration) (Response, []byte, error) {
+	if timeout <= 0 {
+		timeout = c.timeout
 	}
 
-	return c.pushes[route]
-}
-
-// Request ...
-func (c *PClient) Request(route string, data []byte) (Response, []byte, error) {
-	messageID, err := c.client.SendRequest(route, data)
+	messageID, ch, err := c.sendRequestWithChannel(route, data)
 	if err != nil {
 		return nil, nil, err
 	}
-
-	ch := c.getResponseChannelForID(messageID)
+	defer c.removeResponseChannelForID(messageID)
 
 	select {
 	case responseData := <-ch:
@@ -154,8 +224,10 @@ func (c *PClient) Request(route string, data []byte) (Response, []byte, error) {
 		}
 
 		return ret, responseData, nil
-	case <-time.After(c.timeout):
-		return nil, nil, fmt.Errorf("Timeout waiting for response on route %s", route)
+	case <-time.After(timeout):
+		return nil, nil, &TimeoutError{Route: route}
+	case <-c.abort:
+		return nil, nil, constants.ErrBotAborted
 	}
 }
 
@@ -165,22 +237,34 @@ func (c *PClient) Notify(route string, data []byte) error {
 	return err
 }
 
-// ReceivePush ...
-func (c *PClient) ReceivePush(route string, timeout int) (Response, []byte, error) {
-	ch := c.getPushChannelForRoute(route)
-
-	select {
-	case data := <-ch:
-		var ret Response
-		if err := json.Unmarshal(data, &ret); err != nil {
-			err = fmt.Errorf("Error unmarshaling response: %s", err)
-			return nil, nil, err
-		}
+// ReceivePush returns the oldest push received on any of the routes, waiting
+// at most timeout milliseconds for one if none arrived yet
+func (c *PClient) ReceivePush(routes []string, timeout int) (*Push, Response, error) {
+	push, err := c.pushes.receive(routes, time.Duration(timeout)*time.Millisecond, c.abort)
+	if err != nil {
+		return nil, nil, err
+	}
 
-		return ret, data, nil
-	case <-time.After(time.Duration(timeout) * time.Millisecond):
-		return nil, nil, fmt.Errorf("Timeout waiting for push on route %s", route)
+	var ret Response
+	if err := json.Unmarshal(push.Data, &ret); err != nil {
+		err = fmt.Errorf("Error unmarshaling response: %s", err)
+		return nil, nil, err
 	}
+
+	return push, ret, nil
+}
+
+// WatchPushes watches the routes for duration milliseconds and returns the
+// first push for which match is true, or nil if there is none. The response
+// given to match is nil if the push can't be unmarshaled
+func (c *PClient) WatchPushes(routes []string, duration int, match func(*Push, Response) bool) (*Push, error) {
+	return c.pushes.watch(routes, time.Duration(duration)*time.Millisecond, c.abort, func(push *Push) bool {
+		var resp Response
+		if err := json.Unmarshal(push.Data, &resp); err != nil {
+			resp = nil
+		}
+		return match(push, resp)
+	})
 }
 
 // StartListening ...
@@ -190,12 +274,14 @@ func (c *PClient) StartListening() {
 		for m := range channel {
 			switch m.Type {
 			case pitayamessage.Response:
-				ch := c.getResponseChannelForID(m.ID)
-				ch <- m.Data
-				c.removeResponseChannelForID(m.ID)
+				if !c.deliverResponse(m.ID, m.Data) {
+					c.logger.Debugf("Dropped late response to request %d", m.ID)
+				}
 			case pitayamessage.Push:
-				ch := c.getPushChannelForRoute(m.Route)
-				ch <- m.Data
+				if c.pushes.add(m.Route, m.Data) {
+					c.logger.Warnf("Push queue for route %s is full, dropped a push", m.Route)
+					metrics.ReportCount(c.metricsReporter, constants.PushDroppedCount, map[string]string{"route": m.Route}, 1, c.logger)
+				}
 			default:
 				panic("Unknown message type")
 			}
@@ -0,0 +1,35 @@
+package bot
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPClientResponseChannels(t *testing.T) {
+	c := &PClient{responses: make(map[uint]chan []byte), early: make(map[uint][]byte)}
+	ch := make(chan []byte, 1)
+	c.responses[1] = ch
+
+	assert.True(t, c.deliverResponse(1, []byte("response")))
+	assert.Equal(t, []byte("response"), <-ch)
+
+	// late responses don't leave channels behind
+	c.removeResponseChannelForID(1)
+	assert.False(t, c.deliverResponse(1, []byte("late")))
+	assert.False(t, c.deliverResponse(2, []byte("unknown")))
+	assert.Empty(t, c.responses)
+	assert.Empty(t, c.early)
+}
+
+func TestPClientEarlyResponses(t *testing.T) {
+	c := &PClient{responses: make(map[uint]chan []byte), early: make(map[uint][]byte)}
+
+	// responses arriving while a request is sent are kept until it is done
+	c.sending = 1
+	assert.True(t, c.deliverResponse(3, []byte("early")))
+	assert.Equal(t, map[uint][]byte{3: []byte("early")}, c.early)
+
+	c.doneSending()
+	assert.Empty(t, c.early)
+}
@@ -0,0 +1,185 @@
+package bot
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/topfreegames/pitaya-bot/constants"
+)
+
+// Policies applied when a push arrives on a route whose queue is full
+const (
+	// PushDropOldest discards the oldest queued push to make room
+	PushDropOldest = "oldest"
+	// PushDropNewest discards the push that just arrived
+	PushDropNewest = "newest"
+)
+
+// Push is a message pushed by the server to the bot
+type Push struct {
+	Route string
+	Data  []byte
+	// seq is the arrival order of the push among all routes
+	seq uint64
+}
+
+// pushQueues holds the pushes received on each route until the bot listens
+// to them. Queues are bounded, so routes the spec never listens to can't
+// block the delivery of other messages
+type pushQueues struct {
+	mutex      sync.Mutex
+	queues     map[string][]*Push
+	arrived    chan struct{}
+	seq        uint64
+	size       int
+	dropPolicy string
+}
+
+func newPushQueues(size int, dropPolicy string) (*pushQueues, error) {
+	if size <= 0 {
+		return nil, fmt.Errorf("invalid push queue size %d", size)
+	}
+	if dropPolicy != PushDropOldest && dropPolicy != PushDropNewest {
+		return nil, fmt.Errorf("invalid push drop policy %s", dropPolicy)
+	}
+
+	return &pushQueues{
+		queues:     make(map[string][]*Push),
+		arrived:    make(chan struct{}),
+		size:       size,
+		dropPolicy: dropPolicy,
+	}, nil
+}
+
+// add enqueues the push and returns true if a push was dropped to respect
+// the queue size
+func (q *pushQueues) add(route string, data []byte) bool {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	dropped := false
+	queue := q.queues[route]
+	if len(queue) >= q.size {
+		dropped = true
+		if q.dropPolicy == PushDropNewest {
+			return dropped
+		}
+		queue = queue[1:]
+	}
+	q.seq++
+	q.queues[route] = append(queue, &Push{Route: route, Data: data, seq: q.seq})
+
+	// wake up everyone waiting for a push
+	close(q.arrived)
+	q.arrived = make(chan struct{})
+	return dropped
+}
+
+// pop removes the oldest push queued on any of the routes. If there is
+// none, it returns a channel that is closed when the next push arrives
+func (q *pushQueues) pop(routes []string) (*Push, <-chan struct{}) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	var oldest *Push
+	for _, route := range routes {
+		if queue := q.queues[route]; len(queue) > 0 && (oldest == nil || queue[0].seq < oldest.seq) {
+			oldest = queue[0]
+		}
+	}
+	if oldest == nil {
+		return nil, q.arrived
+	}
+
+	q.queues[oldest.Route] = q.queues[oldest.Route][1:]
+	return oldest, nil
+}
+
+// receive returns the oldest push on any of the routes, including pushes
+// that arrived before it was called, waiting at most timeout for one to arrive
+// or until abort is closed
+func (q *pushQueues) receive(routes []string, timeout time.Duration, abort <-chan struct{}) (*Push, error) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for {
+		push, arrived := q.pop(routes)
+		if push != nil {
+			return push, nil
+		}
+
+		select {
+		case <-arrived:
+		case <-timer.C:
+			return nil, &TimeoutError{Route: strings.Join(routes, ", "), Push: true}
+		case <-abort:
+			return nil, constants.ErrBotAborted
+		}
+	}
+}
+
+// since returns the pushes queued on any of the routes that arrived after
+// the push with the given seq, in arrival order, and a channel that is
+// closed when the next push arrives
+func (q *pushQueues) since(routes []string, seq uint64) ([]*Push, <-chan struct{}) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	var ret []*Push
+	for _, route := range routes {
+		for _, push := range q.queues[route] {
+			if push.seq > seq {
+				ret = append(ret, push)
+			}
+		}
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i].seq < ret[j].seq })
+	return ret, q.arrived
+}
+
+// watch returns the first push on any of the routes for which match is
+// true, including pushes that arrived before it was called, or nil if none
+// matches within duration. Pushes are left in their queues. It fails if abort
+// is closed before duration is over
+func (q *pushQueues) watch(routes []string, duration time.Duration, abort <-chan struct{}, match func(*Push) bool) (*Push, error) {
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+
+	var seq uint64
+	for {
+		pushes, arrived := q.since(routes, seq)
+		for _, push := range pushes {
+			if match(push) {
+				return push, nil
+			}
+			seq = push.seq
+		}
+
+		select {
+		case <-arrived:
+		case <-timer.C:
+			return nil, nil
+		case <-abort:
+			return nil, constants.ErrBotAborted
+		}
+	}
+}
+
+// drain empties the queues and returns the number of pushes that were never
+// consumed on each route
+func (q *pushQueues) drain() map[string]int {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	ret := make(map[string]int)
+	for route, queue := range q.queues {
+		if len(queue) > 0 {
+			ret[route] = len(queue)
+		}
+	}
+	q.queues = make(map[string][]*Push)
+	return ret
+}
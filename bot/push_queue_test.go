@@ -0,0 +1,140 @@
+package bot
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/topfreegames/pitaya-bot/constants"
+)
+
+func TestNewPushQueues(t *testing.T) {
+	var newPushQueuesTable = map[string]struct {
+		size       int
+		dropPolicy string
+		err        error
+	}{
+		"oldest":         {10, PushDropOldest, nil},
+		"newest":         {10, PushDropNewest, nil},
+		"invalid_size":   {0, PushDropOldest, errors.New("invalid push queue size 0")},
+		"invalid_policy": {10, "random", errors.New("invalid push drop policy random")},
+	}
+
+	for name, table := range newPushQueuesTable {
+		t.Run(name, func(t *testing.T) {
+			_, err := newPushQueues(table.size, table.dropPolicy)
+			assert.Equal(t, table.err, err)
+		})
+	}
+}
+
+func TestPushQueuesDropPolicy(t *testing.T) {
+	var dropPolicyTable = map[string]struct {
+		dropPolicy string
+		received   []string
+	}{
+		"oldest": {PushDropOldest, []string{"2", "3"}},
+		"newest": {PushDropNewest, []string{"1", "2"}},
+	}
+
+	for name, table := range dropPolicyTable {
+		t.Run(name, func(t *testing.T) {
+			q, err := newPushQueues(2, table.dropPolicy)
+			assert.NoError(t, err)
+
+			assert.False(t, q.add("room.update", []byte("1")))
+			assert.False(t, q.add("room.update", []byte("2")))
+			assert.True(t, q.add("room.update", []byte("3")))
+			assert.False(t, q.add("room.start", []byte("4")))
+
+			for _, expected := range table.received {
+				push, err := q.receive([]string{"room.update"}, time.Millisecond, nil)
+				assert.NoError(t, err)
+				assert.Equal(t, expected, string(push.Data))
+			}
+			assert.Equal(t, map[string]int{"room.start": 1}, q.drain())
+			assert.Empty(t, q.drain())
+		})
+	}
+}
+
+func TestPushQueuesReceive(t *testing.T) {
+	q, err := newPushQueues(10, PushDropOldest)
+	assert.NoError(t, err)
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		q.add("room.update", []byte("ignored"))
+		q.add("room.start", []byte("start"))
+	}()
+
+	push, err := q.receive([]string{"room.start"}, time.Second, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "start", string(push.Data))
+
+	_, err = q.receive([]string{"room.start"}, 10*time.Millisecond, nil)
+	assert.Equal(t, &TimeoutError{Route: "room.start", Push: true}, err)
+}
+
+func TestPushQueuesReceiveAnyOf(t *testing.T) {
+	q, err := newPushQueues(10, PushDropOldest)
+	assert.NoError(t, err)
+
+	q.add("match.cancelled", []byte("cancelled"))
+	q.add("room.update", []byte("ignored"))
+	q.add("match.found", []byte("found"))
+
+	routes := []string{"match.found", "match.cancelled"}
+	push, err := q.receive(routes, time.Millisecond, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "match.cancelled", push.Route)
+
+	push, err = q.receive(routes, time.Millisecond, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "match.found", push.Route)
+
+	_, err = q.receive(routes, time.Millisecond, nil)
+	assert.Equal(t, &TimeoutError{Route: "match.found, match.cancelled", Push: true}, err)
+}
+
+func TestPushQueuesWatch(t *testing.T) {
+	q, err := newPushQueues(10, PushDropOldest)
+	assert.NoError(t, err)
+
+	q.add("player.reward", []byte("gold"))
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		q.add("player.kick", []byte("kick"))
+		q.add("player.reward", []byte("gems"))
+	}()
+
+	isGems := func(push *Push) bool { return string(push.Data) == "gems" }
+	push, err := q.watch([]string{"player.reward"}, time.Second, nil, isGems)
+	assert.NoError(t, err)
+	assert.Equal(t, "gems", string(push.Data))
+
+	push, err = q.watch([]string{"player.reward"}, 10*time.Millisecond, nil, func(push *Push) bool { return false })
+	assert.NoError(t, err)
+	assert.Nil(t, push)
+	push, err = q.watch([]string{"player.ban"}, 10*time.Millisecond, nil, func(push *Push) bool { return true })
+	assert.NoError(t, err)
+	assert.Nil(t, push)
+
+	// watched pushes are not consumed
+	assert.Equal(t, map[string]int{"player.reward": 2, "player.kick": 1}, q.drain())
+}
+
+func TestPushQueuesAbort(t *testing.T) {
+	q, err := newPushQueues(10, PushDropOldest)
+	assert.NoError(t, err)
+	abort := make(chan struct{})
+	close(abort)
+
+	_, err = q.receive([]string{"room.start"}, time.Minute, abort)
+	assert.Equal(t, constants.ErrBotAborted, err)
+
+	push, err := q.watch([]string{"room.start"}, time.Minute, abort, func(push *Push) bool { return true })
+	assert.Equal(t, constants.ErrBotAborted, err)
+	assert.Nil(t, push)
+}
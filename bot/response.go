@@ -16,6 +16,10 @@ func (e Expr) tokenize() []string {
 	if strings.HasPrefix(string(e), "$response") {
 		return tokenSplit(string(e)[9:])
 	}
+	if e == "" {
+		// the whole response
+		return nil
+	}
 	return []string{string(e)}
 }
 
@@ -37,10 +41,26 @@ func tokenSplit(str string) []string {
 }
 
 func tryExtractValue(resp *Response, expr Expr, exprType string) (interface{}, error) {
+	value, err := extractValue(resp, expr)
+	if err != nil {
+		return nil, err
+	}
+
+	finalValue, err := assertType(value, exprType)
+	if err != nil {
+		return nil, err
+	}
+
+	return finalValue, nil
+}
+
+// extractValue walks the response following the expression tokens and
+// returns the value found, without asserting its type
+func extractValue(resp *Response, expr Expr) (interface{}, error) {
 	tokens := expr.tokenize()
 	var container interface{} = (interface{})(*resp)
 	var ok bool
-	for i, token := range tokens {
+	for _, token := range tokens {
 		switch container.(type) {
 		case []interface{}:
 			if index, err := strconv.Atoi(token); err == nil {
@@ -56,18 +76,10 @@ func tryExtractValue(resp *Response, expr Expr, exprType string) (interface{}, e
 				return nil, fmt.Errorf("token '%s' not found within expr %s", token, expr)
 			}
 		default:
-			if i == len(tokens)-1 {
-				break // Found value. Exit loop
-			}
-
+			// tokens remain but the value found can't be walked into
 			return nil, fmt.Errorf("malformed spec file. expr %s doesn't match the object received", expr)
 		}
 	}
 
-	finalValue, err := assertType(container, exprType)
-	if err != nil {
-		return nil, err
-	}
-
-	return finalValue, nil
+	return container, nil
 }
@@ -13,6 +13,7 @@ func TestTokenize(t *testing.T) {
 		result []string
 	}{
 		"value":            {"value", []string{"value"}},
+		"empty":            {"", nil},
 		"response_object":  {"$response.value1.value2", []string{"value1", "value2"}},
 		"response_map":     {"$response[\"value1\"][\"value2\"]", []string{"value1", "value2"}},
 		"response_special": {"$response[\"value[1]\"][\"value.2\"].value3", []string{"value[1]", "value.2", "value3"}},
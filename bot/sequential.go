@@ -3,6 +3,10 @@ package bot
 import (
 	"encoding/json"
 	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
@@ -10,6 +14,7 @@ import (
 	"github.com/topfreegames/pitaya-bot/custom"
 	"github.com/topfreegames/pitaya-bot/metrics"
 	"github.com/topfreegames/pitaya-bot/models"
+	"github.com/topfreegames/pitaya-bot/results"
 	"github.com/topfreegames/pitaya-bot/storage"
 	"github.com/topfreegames/pitaya/v2/session"
 )
@@ -24,14 +29,27 @@ type SequentialBot struct {
 	metricsReporter []metrics.Reporter
 	spec            *models.Spec
 	storage         storage.Storage
+	variables       map[string]interface{}
+	lastResponse    Response
+	random          *rand.Rand
+	results         results.Sink
+	abort           <-chan struct{}
 }
 
+// loopIndexKey is the variable holding the index of the innermost loop
+const loopIndexKey = "$loop.index"
+
+// pushRouteKey is the variable holding the route of the last push received
+const pushRouteKey = "$push.route"
+
 // NewSequentialBot returns a new sequantial bot instance
 func NewSequentialBot(
 	config *viper.Viper,
 	spec *models.Spec,
 	id int,
 	mr []metrics.Reporter,
+	sink results.Sink,
+	abort <-chan struct{},
 	logger logrus.FieldLogger,
 ) (Bot, error) {
 	store, err := storage.NewStorage(config)
@@ -39,6 +57,11 @@ func NewSequentialBot(
 		return nil, err
 	}
 
+	seed := config.GetInt64("bot.operation.seed")
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+
 	bot := &SequentialBot{
 		config:          config,
 		host:            config.GetString("server.host"),
@@ -47,6 +70,10 @@ func NewSequentialBot(
 		metricsReporter: mr,
 		spec:            spec,
 		storage:         store,
+		variables:       map[string]interface{}{},
+		random:          rand.New(rand.NewSource(seed + int64(id))),
+		results:         sink,
+		abort:           abort,
 	}
 
 	if err = bot.Connect(); err != nil {
@@ -79,40 +106,106 @@ func (b *SequentialBot) Run() (err error) {
 		}
 	}()
 
+	initial := b.storage.Clone()
+	maxRestarts := b.config.GetInt("bot.operation.maxRestarts")
+	for restarts := 0; ; restarts++ {
+		err = b.runSteps()
+		restart, ok := err.(*RestartError)
+		if !ok {
+			return
+		}
+		if restarts >= maxRestarts {
+			b.logger.Warnf("reached max restarts (%d)", maxRestarts)
+			return restart.Err
+		}
+
+		b.logger.Infof("Restarting sequential operations (%d/%d)", restarts+1, maxRestarts)
+		b.resetState(initial)
+		if err = b.Reconnect(); err != nil {
+			return err
+		}
+	}
+}
+
+// resetState drops what a failed attempt left behind, so a restart begins
+// from the storage the bot had before running its operations
+func (b *SequentialBot) resetState(initial storage.Storage) {
+	b.storage = initial.Clone()
+	b.variables = map[string]interface{}{}
+	b.lastResponse = nil
+}
+
+// scope returns the storage the operations resolve their values from
+func (b *SequentialBot) scope() storage.Storage {
+	return &scope{Storage: b.storage, variables: b.variables}
+}
+
+func (b *SequentialBot) runSteps() error {
 	steps := b.spec.SequentialOperations
 	for idx, step := range steps {
-		err = b.runOperation(step)
-		if err != nil {
+		if err := b.runOperation(step); err != nil {
 			b.logger.WithError(err).Warnf("failed sequential step %d (%s/%s)", idx, step.Type, step.URI)
-			return
+			if err == constants.ErrBotAborted {
+				return err
+			}
+			if restart, ok := err.(*RestartError); ok {
+				return &RestartError{Err: &OperationError{Step: idx, Type: step.Type, URI: step.URI, Err: restart.Err}}
+			}
+			return &OperationError{Step: idx, Type: step.Type, URI: step.URI, Err: err}
 		}
 	}
 
-	return
+	return nil
 }
 
 func (b *SequentialBot) runRequest(op *models.Operation) error {
 	b.logger.Debug("Executing request to: " + op.URI)
-	route := op.URI
-	args, err := buildArgByType(op.Args, "object", b.storage)
+	args, err := buildArgByType(op.Args, "object", b.scope())
 	if err != nil {
 		return err
 	}
 
-	resp, rawResp, err := sendRequest(args, route, b.client, b.metricsReporter, b.logger)
+	for attempt := 1; ; attempt++ {
+		err = b.tryRequest(op, args)
+		reason, retry := retryReason(op.Retry, err)
+		if !retry || attempt >= op.Retry.MaxAttempts {
+			return err
+		}
+
+		b.logger.WithError(err).Warnf("Retrying request to %s (attempt %d/%d)", op.URI, attempt+1, op.Retry.MaxAttempts)
+		metrics.ReportCount(b.metricsReporter, constants.RetryCount, map[string]string{"route": op.URI, "reason": reason}, 1, b.logger)
+		if op.Retry.Backoff != nil {
+			b.pause(op.Retry.Backoff)
+		}
+		if b.aborted() {
+			return constants.ErrBotAborted
+		}
+	}
+}
+
+func (b *SequentialBot) tryRequest(op *models.Operation, args interface{}) error {
+	timeout := time.Duration(op.Timeout) * time.Millisecond
+	start := time.Now()
+	resp, rawResp, err := sendRequest(args, op.URI, timeout, b.client, b.metricsReporter, b.logger)
+	elapsed := time.Since(start)
+	if err == constants.ErrBotAborted {
+		return err
+	}
 	if err != nil {
+		b.recordSample(results.KindRequest, op.URI, start, elapsed, sampleOutcome(err), 0)
 		return err
 	}
+	b.lastResponse = resp
 
 	b.logger.Debug("validating expectations")
-	err = validateExpectations(op.Expect, resp, b.storage)
+	err = b.checkExpectations(op, op.URI, op.Expect, resp, rawResp)
+	b.recordSample(results.KindRequest, op.URI, start, elapsed, sampleOutcome(err), len(rawResp))
 	if err != nil {
-		return NewExpectError(err, rawResp, op.Expect)
+		return err
 	}
-	b.logger.Debug("received valid response")
 
 	b.logger.Debug("storing data")
-	err = storeData(op.Store, b.storage, resp)
+	err = storeData(op.Store, b.scope(), resp)
 	if err != nil {
 		return err
 	}
@@ -121,15 +214,61 @@ func (b *SequentialBot) runRequest(op *models.Operation) error {
 	return nil
 }
 
+// checkExpectations validates the response received on route, failed
+// expectations of soft assert operations are only reported
+func (b *SequentialBot) checkExpectations(op *models.Operation, route string, expect models.ExpectSpec, resp Response, rawResp []byte) error {
+	err := validateExpectations(expect, resp, b.scope())
+	if err == nil {
+		b.logger.Debug("received valid response")
+		return nil
+	}
+	kind := metrics.SummaryKindPush
+	if op.Type == "request" {
+		kind = metrics.SummaryKindRequest
+	}
+	metrics.ReportCount(b.metricsReporter, constants.ExpectFailureCount, map[string]string{"route": route, "kind": kind}, 1, b.logger)
+
+	expectErr := NewExpectError(err, rawResp, expect)
+	if !op.SoftAssert {
+		return expectErr
+	}
+
+	b.reportFailure(op, "soft", expectErr)
+	return nil
+}
+
+// reportFailure logs and counts a failure that doesn't stop the bot
+func (b *SequentialBot) reportFailure(op *models.Operation, policy string, err error) {
+	b.logger.WithError(err).Warnf("operation %s/%s failed (%s)", op.Type, op.URI, policy)
+	tags := map[string]string{"type": op.Type, "route": op.URI, "policy": policy}
+	metrics.ReportCount(b.metricsReporter, constants.FailureCount, tags, 1, b.logger)
+}
+
+// retryReason returns whether the policy retries the error and why
+func retryReason(policy *models.RetryPolicy, err error) (string, bool) {
+	if policy == nil || err == nil {
+		return "", false
+	}
+
+	switch err.(type) {
+	case *TimeoutError:
+		return "timeout", policy.OnTimeout
+	case *ExpectError:
+		return "expect", policy.OnExpectFailure
+	}
+
+	return "", false
+}
+
 func (b *SequentialBot) runNotify(op *models.Operation) error {
 	b.logger.Debug("Executing notify to: " + op.URI)
 	route := op.URI
-	args, err := buildArgByType(op.Args, "object", b.storage)
+	args, err := buildArgByType(op.Args, "object", b.scope())
 	if err != nil {
 		return err
 	}
 
-	err = sendNotify(args, route, b.client)
+	err = sendNotify(args, route, b.client, b.metricsReporter, b.logger)
 	if err != nil {
 		return err
 	}
@@ -147,7 +286,7 @@ func (b *SequentialBot) runFunction(op *models.Operation) error {
 		b.Disconnect()
 	case "connect":
 		host := b.host
-		args, err := buildArgByType(op.Args, "object", b.storage)
+		args, err := buildArgByType(op.Args, "object", b.scope())
 		if err != nil {
 			return err
 		}
@@ -161,9 +300,9 @@ func (b *SequentialBot) runFunction(op *models.Operation) error {
 				host = h
 			}
 		}
-		b.Connect(host)
+		return b.Connect(host)
 	case "reconnect":
-		b.Reconnect()
+		return b.Reconnect()
 	default:
 		return fmt.Errorf("Unknown function: %s", fName)
 	}
@@ -172,21 +311,109 @@ func (b *SequentialBot) runFunction(op *models.Operation) error {
 }
 
 func (b *SequentialBot) listenToPush(op *models.Operation) error {
-	b.logger.Debug("Waiting for push on route: " + op.URI)
-	resp, rawResp, err := b.client.ReceivePush(op.URI, op.Timeout)
+	if op.Ordered {
+		return b.listenInOrder(op)
+	}
+
+	routes := op.ListenRoutes()
+	b.logger.Debugf("Waiting for push on routes: %v", routes)
+	start := time.Now()
+	push, resp, err := receivePush(routes, op.Timeout, b.client, b.metricsReporter, b.logger)
+	elapsed := time.Since(start)
+	if err == constants.ErrBotAborted {
+		return err
+	}
 	if err != nil {
+		b.recordSample(results.KindPush, strings.Join(routes, ","), start, elapsed, sampleOutcome(err), 0)
 		return err
 	}
 
+	err = b.handlePush(op, op.GetRoute(push.Route), push, resp)
+	b.recordSample(results.KindPush, push.Route, start, elapsed, sampleOutcome(err), len(push.Data))
+	return err
+}
+
+// watchNoPush fails if a push matching the operation expectations arrives on
+// any of its routes within the operation timeout
+func (b *SequentialBot) watchNoPush(op *models.Operation) error {
+	routes := op.ListenRoutes()
+	b.logger.Debugf("Watching for unexpected pushes on routes: %v", routes)
+	push, err := b.client.WatchPushes(routes, op.Timeout, func(push *Push, resp Response) bool {
+		expect := op.Expect
+		if route := op.GetRoute(push.Route); route != nil {
+			expect = mergeExpect(op.Expect, route.Expect)
+		}
+		return evaluateCondition(expect, resp, b.scope())
+	})
+	if err != nil {
+		return err
+	}
+	if push != nil {
+		return fmt.Errorf("Unexpected push on route %s: %s", push.Route, push.Data)
+	}
+
+	b.logger.Debug("no unexpected push received")
+	return nil
+}
+
+// listenInOrder waits for a push on each route of the operation, failing if
+// they don't arrive in the given order within the operation timeout
+func (b *SequentialBot) listenInOrder(op *models.Operation) error {
+	deadline := time.Now().Add(time.Duration(op.Timeout) * time.Millisecond)
+	var last *Push
+	for _, route := range op.Routes {
+		b.logger.Debug("Waiting for push on route: " + route.URI)
+		remaining := int(time.Until(deadline) / time.Millisecond)
+		if remaining < 0 {
+			remaining = 0
+		}
+
+		start := time.Now()
+		push, resp, err := receivePush([]string{route.URI}, remaining, b.client, b.metricsReporter, b.logger)
+		elapsed := time.Since(start)
+		if err == constants.ErrBotAborted {
+			return err
+		}
+		if err != nil {
+			b.recordSample(results.KindPush, route.URI, start, elapsed, sampleOutcome(err), 0)
+			return err
+		}
+		if last != nil && push.seq < last.seq {
+			b.recordSample(results.KindPush, push.Route, start, elapsed, results.OutcomeError, len(push.Data))
+			return fmt.Errorf("push on route %s arrived before push on route %s", push.Route, last.Route)
+		}
+		last = push
+
+		err = b.handlePush(op, route, push, resp)
+		b.recordSample(results.KindPush, push.Route, start, elapsed, sampleOutcome(err), len(push.Data))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// handlePush validates and stores the push with both the operation and the
+// route blocks, route may be nil
+func (b *SequentialBot) handlePush(op *models.Operation, route *models.PushRoute, push *Push, resp Response) error {
+	b.lastResponse = resp
+	b.variables[pushRouteKey] = push.Route
+
+	expect, store := op.Expect, op.Store
+	if route != nil {
+		expect = mergeExpect(op.Expect, route.Expect)
+		store = mergeStore(op.Store, route.Store)
+	}
+
 	b.logger.Debug("validating expectations")
-	err = validateExpectations(op.Expect, resp, b.storage)
+	err := b.checkExpectations(op, push.Route, expect, resp, push.Data)
 	if err != nil {
-		return NewExpectError(err, rawResp, op.Expect)
+		return err
 	}
-	b.logger.Debug("received valid response")
 
 	b.logger.Debug("storing data")
-	err = storeData(op.Store, b.storage, resp)
+	err = storeData(store, b.scope(), resp)
 	if err != nil {
 		return err
 	}
@@ -195,13 +422,179 @@ func (b *SequentialBot) listenToPush(op *models.Operation) error {
 	return nil
 }
 
+func (b *SequentialBot) runLoop(op *models.Operation) error {
+	maxIterations := op.MaxIterations
+	if maxIterations <= 0 {
+		maxIterations = b.config.GetInt("bot.operation.maxLoopIterations")
+	}
+
+	// restore the index of the enclosing loop, if any, when done
+	if outerIndex, ok := b.variables[loopIndexKey]; ok {
+		defer func() { b.variables[loopIndexKey] = outerIndex }()
+	} else {
+		defer delete(b.variables, loopIndexKey)
+	}
+
+	for i := 0; op.Count <= 0 || i < op.Count; i++ {
+		if len(op.While) > 0 && !evaluateCondition(op.While, b.lastResponse, b.scope()) {
+			break
+		}
+
+		if op.Count <= 0 && i >= maxIterations {
+			return fmt.Errorf("loop exceeded max iterations (%d)", maxIterations)
+		}
+
+		b.logger.Debugf("Running loop iteration %d", i)
+		b.variables[loopIndexKey] = i
+		if err := b.runOperations(op.Operations); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (b *SequentialBot) runIf(op *models.Operation) error {
+	if evaluateCondition(op.Condition, b.lastResponse, b.scope()) {
+		b.logger.Debug("Condition holds, running operations")
+		return b.runOperations(op.Operations)
+	}
+
+	b.logger.Debug("Condition doesn't hold, running else operations")
+	return b.runOperations(op.Else)
+}
+
+func (b *SequentialBot) runChoice(op *models.Operation) error {
+	idx := pickChoice(op.Choices, b.random)
+	choice := op.Choices[idx]
+	branch := choice.Name
+	if branch == "" {
+		branch = strconv.Itoa(idx)
+	}
+
+	b.logger.Debugf("Choice %s took branch %s", op.URI, branch)
+	metrics.ReportCount(b.metricsReporter, constants.ChoiceCount, map[string]string{"choice": op.URI, "branch": branch}, 1, b.logger)
+	return b.runOperations(choice.Operations)
+}
+
+// pickChoice returns the index of a choice selected at random according to
+// the choices weights
+func pickChoice(choices []*models.Choice, random *rand.Rand) int {
+	total := 0.0
+	for _, choice := range choices {
+		total += choice.Weight
+	}
+
+	r := random.Float64() * total
+	for idx, choice := range choices {
+		if r < choice.Weight {
+			return idx
+		}
+		r -= choice.Weight
+	}
+
+	return len(choices) - 1
+}
+
+func (b *SequentialBot) runOperations(ops []*models.Operation) error {
+	for idx, step := range ops {
+		if err := b.runOperation(step); err != nil {
+			b.logger.WithError(err).Warnf("failed nested step %d (%s/%s)", idx, step.Type, step.URI)
+			return err
+		}
+	}
+
+	return nil
+}
+
 // StartListening ...
 func (b *SequentialBot) startListening() {
 	b.client.StartListening()
 }
 
-// TODO - refactor
+func (b *SequentialBot) runSleep(op *models.Operation) error {
+	b.pause(op.Duration)
+	return nil
+}
+
+func (b *SequentialBot) pause(delay *models.Delay) {
+	duration := sampleDelay(delay, b.random)
+	b.logger.Debugf("Sleeping for %s", duration)
+	select {
+	case <-time.After(duration):
+	case <-b.abort:
+	}
+}
+
+// sampleDelay draws a duration from the delay distribution. Values are
+// clamped to [min, max] when they are set and never negative
+func sampleDelay(delay *models.Delay, random *rand.Rand) time.Duration {
+	var ms float64
+	switch delay.GetDistribution() {
+	case models.DistributionConstant:
+		ms = delay.Value
+	case models.DistributionUniform:
+		ms = delay.Min + random.Float64()*(delay.Max-delay.Min)
+	case models.DistributionNormal:
+		ms = delay.Mean + random.NormFloat64()*delay.StdDev
+	case models.DistributionExponential:
+		ms = random.ExpFloat64() * delay.Mean
+	}
+
+	if delay.Max > 0 && ms > delay.Max {
+		ms = delay.Max
+	}
+	if ms < delay.Min {
+		ms = delay.Min
+	}
+
+	return time.Duration(ms * float64(time.Millisecond))
+}
+
+// aborted returns true if the bot must stop running operations
+func (b *SequentialBot) aborted() bool {
+	select {
+	case <-b.abort:
+		return true
+	default:
+		return false
+	}
+}
+
 func (b *SequentialBot) runOperation(op *models.Operation) error {
+	if b.aborted() {
+		return constants.ErrBotAborted
+	}
+
+	if err := b.executeOperation(op); err != nil {
+		if err == constants.ErrBotAborted {
+			return err
+		}
+		if _, ok := err.(*RestartError); ok {
+			// a nested operation already decided to restart
+			return err
+		}
+
+		switch op.GetOnFailure() {
+		case models.OnFailureContinue:
+			b.reportFailure(op, models.OnFailureContinue, err)
+			return nil
+		case models.OnFailureRestart:
+			b.reportFailure(op, models.OnFailureRestart, err)
+			return &RestartError{Err: err}
+		}
+		return err
+	}
+
+	if op.ThinkTime != nil {
+		b.pause(op.ThinkTime)
+	}
+
+	return nil
+}
+
+// TODO - refactor
+func (b *SequentialBot) executeOperation(op *models.Operation) error {
 	switch op.Type {
 	case "request":
 		return b.runRequest(op)
@@ -211,6 +604,16 @@ func (b *SequentialBot) runOperation(op *models.Operation) error {
 		return b.runFunction(op)
 	case "listen":
 		return b.listenToPush(op)
+	case "noPush":
+		return b.watchNoPush(op)
+	case "loop":
+		return b.runLoop(op)
+	case "if":
+		return b.runIf(op)
+	case "choice":
+		return b.runChoice(op)
+	case "sleep":
+		return b.runSleep(op)
 	}
 
 	return fmt.Errorf("Unknown type: %s", op.Type)
@@ -227,6 +630,41 @@ func (b *SequentialBot) Finalize() error {
 	return nil
 }
 
+// recordSample writes a sample to the results sink, if there is one
+func (b *SequentialBot) recordSample(kind, route string, start time.Time, elapsed time.Duration, outcome string, size int) {
+	if b.results == nil {
+		return
+	}
+
+	b.results.Write(&results.Sample{
+		Time:    start,
+		BotID:   b.id,
+		Spec:    b.spec.Name,
+		Kind:    kind,
+		Route:   route,
+		Latency: float64(elapsed) / float64(time.Millisecond),
+		Outcome: outcome,
+		Bytes:   size,
+	})
+}
+
+// sampleOutcome returns the outcome of a sample that failed with err, which
+// may be nil
+func sampleOutcome(err error) string {
+	if err == nil {
+		return results.OutcomeOK
+	}
+
+	switch err.(type) {
+	case *TimeoutError:
+		return results.OutcomeTimeout
+	case *ExpectError:
+		return results.OutcomeExpectFailed
+	default:
+		return results.OutcomeError
+	}
+}
+
 // Disconnect ...
 func (b *SequentialBot) Disconnect() {
 	b.client.Disconnect()
@@ -265,8 +703,21 @@ func (b *SequentialBot) Connect(hosts ...string) error {
 
 	useTLS := b.config.GetBool("server.tls")
 	timeout := b.config.GetDuration("server.requestTimeout")
-	client, err := NewPClient(b.host, useTLS, handshake, timeout, b.logger, docs, pushinfo)
+	pushes, err := newPushQueues(b.config.GetInt("bot.push.queueSize"), b.config.GetString("bot.push.dropPolicy"))
 	if err != nil {
+		b.logger.WithError(err).Error("Invalid push queue config")
+		return err
+	}
+
+	tags := map[string]string{"spec": b.spec.Name}
+	metrics.ReportCount(b.metricsReporter, constants.ConnectionAttemptCount, tags, 1, b.logger)
+	start := time.Now()
+	client, err := NewPClient(b.host, useTLS, handshake, timeout, b.logger, docs, pushinfo, pushes, b.metricsReporter, b.abort)
+	elapsed := time.Since(start)
+	b.recordSample(results.KindConnect, b.host, start, elapsed, sampleOutcome(err), 0)
+	metrics.ReportSummary(b.metricsReporter, constants.ConnectionTime, tags, float64(elapsed)/float64(time.Millisecond), b.logger)
+	if err != nil {
+		metrics.ReportCount(b.metricsReporter, constants.ConnectionFailureCount, tags, 1, b.logger)
 		b.logger.WithError(err).Error("Unable to create client...")
 		return err
 	}
@@ -277,8 +728,11 @@ func (b *SequentialBot) Connect(hosts ...string) error {
 }
 
 // Reconnect ...
-func (b *SequentialBot) Reconnect() {
+func (b *SequentialBot) Reconnect() error {
 	b.Disconnect()
-	b.Connect()
+	if err := b.Connect(); err != nil {
+		return err
+	}
 	b.logger.Debug("Reconnect done")
+	return nil
 }
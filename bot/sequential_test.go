@@ -0,0 +1,347 @@
+package bot
+
+import (
+	"errors"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/topfreegames/pitaya-bot/constants"
+	"github.com/topfreegames/pitaya-bot/metrics"
+	"github.com/topfreegames/pitaya-bot/models"
+	"github.com/topfreegames/pitaya-bot/results"
+	"github.com/topfreegames/pitaya-bot/storage"
+)
+
+func newTestSequentialBot(store storage.Storage) *SequentialBot {
+	return &SequentialBot{
+		config:    viper.New(),
+		logger:    logrus.New(),
+		spec:      models.NewSpec("test"),
+		storage:   store,
+		variables: map[string]interface{}{},
+		random:    rand.New(rand.NewSource(1)),
+	}
+}
+
+func TestSequentialRunLoop(t *testing.T) {
+	var runLoopTable = map[string]struct {
+		op    *models.Operation
+		store *storage.MemoryStorage
+		err   error
+	}{
+		"skip_while_false": {&models.Operation{Type: "loop", While: models.ExpectSpec{
+			"$store.searching": {Type: "bool", Value: true},
+		}, Operations: []*models.Operation{{Type: "unknown"}}}, &storage.MemoryStorage{"searching": false}, nil},
+		"fail_nested": {&models.Operation{Type: "loop", Count: 3, Operations: []*models.Operation{
+			{Type: "unknown"},
+		}}, &storage.MemoryStorage{}, errors.New("Unknown type: unknown")},
+		"max_iterations": {&models.Operation{Type: "loop", MaxIterations: 2, While: models.ExpectSpec{
+			"$store.searching": {Type: "bool", Value: true},
+		}, Operations: []*models.Operation{{Type: "loop", Count: 1, While: models.ExpectSpec{
+			"$store.searching": {Type: "bool", Value: false},
+		}}}}, &storage.MemoryStorage{"searching": true}, errors.New("loop exceeded max iterations (2)")},
+	}
+
+	for name, table := range runLoopTable {
+		t.Run(name, func(t *testing.T) {
+			b := newTestSequentialBot(table.store)
+			err := b.runOperation(table.op)
+			assert.Equal(t, table.err, err)
+			assert.NotContains(t, b.variables, loopIndexKey)
+		})
+	}
+}
+
+func TestSequentialRunLoopRestoresIndex(t *testing.T) {
+	store := &storage.MemoryStorage{}
+	b := newTestSequentialBot(store)
+	b.variables[loopIndexKey] = 7
+	err := b.runOperation(&models.Operation{Type: "loop", Count: 2, Operations: []*models.Operation{
+		{Type: "loop", Count: 1, While: models.ExpectSpec{"$store.done": {Operator: "exists"}}},
+	}})
+	assert.NoError(t, err)
+	assert.Equal(t, 7, b.variables[loopIndexKey])
+	assert.Equal(t, &storage.MemoryStorage{}, store)
+}
+
+func TestSequentialRunIf(t *testing.T) {
+	failing := []*models.Operation{{Type: "unknown"}}
+	var runIfTable = map[string]struct {
+		op           *models.Operation
+		lastResponse Response
+		err          error
+	}{
+		"then_branch": {&models.Operation{Type: "if", Condition: models.ExpectSpec{
+			"$response.code": {Type: "string", Value: "404"},
+		}, Operations: failing}, map[string]interface{}{"code": "404"}, errors.New("Unknown type: unknown")},
+		"else_branch": {&models.Operation{Type: "if", Condition: models.ExpectSpec{
+			"$response.code": {Type: "string", Value: "404"},
+		}, Operations: failing}, map[string]interface{}{"code": "200"}, nil},
+		"else_branch_store": {&models.Operation{Type: "if", Condition: models.ExpectSpec{
+			"$store.playerId": {Operator: "notExists"},
+		}, Else: failing}, nil, errors.New("Unknown type: unknown")},
+	}
+
+	for name, table := range runIfTable {
+		t.Run(name, func(t *testing.T) {
+			b := newTestSequentialBot(&storage.MemoryStorage{"playerId": "123456"})
+			b.lastResponse = table.lastResponse
+			err := b.runOperation(table.op)
+			assert.Equal(t, table.err, err)
+		})
+	}
+}
+
+func TestPickChoice(t *testing.T) {
+	choices := []*models.Choice{
+		{Name: "shop", Weight: 60},
+		{Name: "never", Weight: 0},
+		{Name: "match", Weight: 30},
+		{Name: "chat", Weight: 10},
+	}
+
+	random := rand.New(rand.NewSource(42))
+	picks := make([]int, len(choices))
+	for i := 0; i < 10000; i++ {
+		picks[pickChoice(choices, random)]++
+	}
+
+	assert.Equal(t, 0, picks[1])
+	assert.InDelta(t, 6000, picks[0], 300)
+	assert.InDelta(t, 3000, picks[2], 300)
+	assert.InDelta(t, 1000, picks[3], 300)
+}
+
+func TestSequentialRunChoice(t *testing.T) {
+	b := newTestSequentialBot(&storage.MemoryStorage{})
+	err := b.runOperation(&models.Operation{Type: "choice", URI: "player-mix", Choices: []*models.Choice{
+		{Weight: 0, Operations: []*models.Operation{{Type: "loop", Count: 1}}},
+		{Weight: 1, Operations: []*models.Operation{{Type: "unknown"}}},
+	}})
+	assert.Equal(t, errors.New("Unknown type: unknown"), err)
+}
+
+func TestSampleDelay(t *testing.T) {
+	random := rand.New(rand.NewSource(42))
+
+	assert.Equal(t, 150*time.Millisecond, sampleDelay(&models.Delay{Value: 150}, random))
+
+	var total time.Duration
+	for i := 0; i < 1000; i++ {
+		d := sampleDelay(&models.Delay{Distribution: "uniform", Min: 100, Max: 200}, random)
+		assert.True(t, d >= 100*time.Millisecond && d <= 200*time.Millisecond)
+
+		d = sampleDelay(&models.Delay{Distribution: "normal", Mean: 10, StdDev: 50}, random)
+		assert.True(t, d >= 0)
+
+		d = sampleDelay(&models.Delay{Distribution: "exponential", Mean: 100, Max: 300}, random)
+		assert.True(t, d <= 300*time.Millisecond)
+
+		total += sampleDelay(&models.Delay{Distribution: "exponential", Mean: 100}, random)
+	}
+	assert.InDelta(t, 100, float64(total/time.Millisecond)/1000, 15)
+}
+
+func TestSequentialRunSleep(t *testing.T) {
+	b := newTestSequentialBot(&storage.MemoryStorage{})
+	start := time.Now()
+	err := b.runOperation(&models.Operation{
+		Type:      "sleep",
+		Duration:  &models.Delay{Value: 20},
+		ThinkTime: &models.Delay{Value: 20},
+	})
+	assert.NoError(t, err)
+	assert.True(t, time.Since(start) >= 40*time.Millisecond)
+}
+
+func TestRetryReason(t *testing.T) {
+	timeout := &TimeoutError{Route: "connector.playerHandler.create"}
+	expect := NewExpectError(errors.New("failed"), []byte{}, models.ExpectSpec{})
+
+	var retryReasonTable = map[string]struct {
+		policy *models.RetryPolicy
+		err    error
+		reason string
+		retry  bool
+	}{
+		"no_policy":        {nil, timeout, "", false},
+		"no_error":         {&models.RetryPolicy{MaxAttempts: 3, OnTimeout: true}, nil, "", false},
+		"timeout":          {&models.RetryPolicy{MaxAttempts: 3, OnTimeout: true}, timeout, "timeout", true},
+		"timeout_disabled": {&models.RetryPolicy{MaxAttempts: 3, OnExpectFailure: true}, timeout, "timeout", false},
+		"expect":           {&models.RetryPolicy{MaxAttempts: 3, OnExpectFailure: true}, expect, "expect", true},
+		"expect_disabled":  {&models.RetryPolicy{MaxAttempts: 3, OnTimeout: true}, expect, "expect", false},
+		"other_error":      {&models.RetryPolicy{MaxAttempts: 3, OnTimeout: true, OnExpectFailure: true}, errors.New("closed"), "", false},
+	}
+
+	for name, table := range retryReasonTable {
+		t.Run(name, func(t *testing.T) {
+			reason, retry := retryReason(table.policy, table.err)
+			assert.Equal(t, table.reason, reason)
+			assert.Equal(t, table.retry, retry)
+		})
+	}
+}
+
+func TestSequentialRunOperationAborted(t *testing.T) {
+	abort := make(chan struct{})
+	b := newTestSequentialBot(&storage.MemoryStorage{})
+	b.abort = abort
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		close(abort)
+	}()
+
+	// the sleep is cut short and aborting ignores the failure policies
+	start := time.Now()
+	err := b.runOperation(&models.Operation{Type: "loop", Count: 2, OnFailure: "continue", Operations: []*models.Operation{
+		{Type: "sleep", Duration: &models.Delay{Value: 1000}, OnFailure: "restart"},
+	}})
+	assert.Equal(t, constants.ErrBotAborted, err)
+	assert.True(t, time.Since(start) < time.Second)
+}
+
+func TestSequentialRunSteps(t *testing.T) {
+	unknown := errors.New("Unknown type: unknown")
+
+	var runStepsTable = map[string]struct {
+		steps []*models.Operation
+		err   error
+	}{
+		"passed": {[]*models.Operation{{Type: "sleep", Duration: &models.Delay{Value: 1}}}, nil},
+		"failed": {[]*models.Operation{{Type: "sleep", Duration: &models.Delay{Value: 1}}, {Type: "unknown", URI: "a"}}, &OperationError{Step: 1, Type: "unknown", URI: "a", Err: unknown}},
+		"restart": {[]*models.Operation{{Type: "unknown", URI: "a", OnFailure: "restart"}}, &RestartError{
+			Err: &OperationError{Step: 0, Type: "unknown", URI: "a", Err: unknown},
+		}},
+	}
+
+	for name, table := range runStepsTable {
+		t.Run(name, func(t *testing.T) {
+			b := newTestSequentialBot(&storage.MemoryStorage{})
+			b.spec.SequentialOperations = table.steps
+			assert.Equal(t, table.err, b.runSteps())
+		})
+	}
+}
+
+func TestSequentialResetState(t *testing.T) {
+	initial := &storage.MemoryStorage{"token": "abc"}
+	b := newTestSequentialBot(initial.Clone())
+	b.storage.Set("token", "def")
+	b.variables[loopIndexKey] = 3
+	b.variables[pushRouteKey] = "connector.push"
+	b.lastResponse = map[string]interface{}{"code": 500}
+
+	b.resetState(initial)
+	assert.Equal(t, &storage.MemoryStorage{"token": "abc"}, b.storage)
+	assert.Nil(t, b.lastResponse)
+	assert.Empty(t, b.variables)
+
+	b.storage.Set("token", "ghi")
+	assert.Equal(t, &storage.MemoryStorage{"token": "abc"}, initial)
+}
+
+func TestSequentialRunReconnectFailure(t *testing.T) {
+	b := newTestSequentialBot(&storage.MemoryStorage{})
+	b.client = &PClient{}
+	b.config.Set("bot.operation.maxRestarts", 1)
+	b.config.Set("server.handshake", "{}")
+	b.spec.SequentialOperations = []*models.Operation{{Type: "unknown", OnFailure: "restart"}}
+
+	err := b.Run()
+	assert.EqualError(t, err, "invalid push queue size 0")
+}
+
+func TestSampleOutcome(t *testing.T) {
+	var sampleOutcomeTable = map[string]struct {
+		err     error
+		outcome string
+	}{
+		"ok":           {nil, results.OutcomeOK},
+		"timeout":      {&TimeoutError{Route: "room.join"}, results.OutcomeTimeout},
+		"push_timeout": {&TimeoutError{Route: "room.start", Push: true}, results.OutcomeTimeout},
+		"expect":       {NewExpectError(errors.New("mismatch"), nil, nil), results.OutcomeExpectFailed},
+		"error":        {errors.New("closed"), results.OutcomeError},
+	}
+
+	for name, table := range sampleOutcomeTable {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, table.outcome, sampleOutcome(table.err))
+		})
+	}
+}
+
+func TestSequentialRunOperationOnFailure(t *testing.T) {
+	unknown := errors.New("Unknown type: unknown")
+
+	var onFailureTable = map[string]struct {
+		op  *models.Operation
+		err error
+	}{
+		"abort":    {&models.Operation{Type: "unknown"}, unknown},
+		"continue": {&models.Operation{Type: "unknown", OnFailure: "continue"}, nil},
+		"restart":  {&models.Operation{Type: "unknown", OnFailure: "restart"}, &RestartError{Err: unknown}},
+		"continue_nested": {&models.Operation{Type: "loop", Count: 2, Operations: []*models.Operation{
+			{Type: "unknown", OnFailure: "continue"},
+		}}, nil},
+		"restart_nested": {&models.Operation{Type: "loop", Count: 2, OnFailure: "continue", Operations: []*models.Operation{
+			{Type: "unknown", OnFailure: "restart"},
+		}}, &RestartError{Err: unknown}},
+	}
+
+	for name, table := range onFailureTable {
+		t.Run(name, func(t *testing.T) {
+			b := newTestSequentialBot(&storage.MemoryStorage{})
+			assert.Equal(t, table.err, b.runOperation(table.op))
+		})
+	}
+}
+
+// testReporter keeps the counts reported and the last value of the other
+// metrics, by metric and route
+type testReporter struct {
+	counts map[string]float64
+	values map[string]float64
+}
+
+func newTestReporter() *testReporter {
+	return &testReporter{counts: map[string]float64{}, values: map[string]float64{}}
+}
+
+func (r *testReporter) ReportCount(metric string, tags map[string]string, count float64) error {
+	r.counts[metric+":"+tags["route"]] += count
+	return nil
+}
+
+func (r *testReporter) ReportSummary(metric string, tags map[string]string, value float64) error {
+	r.values[metric+":"+tags["route"]] = value
+	return nil
+}
+
+func (r *testReporter) ReportHistogram(metric string, tags map[string]string, value float64) error {
+	r.values[metric+":"+tags["route"]] = value
+	return nil
+}
+
+func (r *testReporter) ReportGauge(metric string, tags map[string]string, value float64) error {
+	r.values[metric+":"+tags["route"]] = value
+	return nil
+}
+
+func TestSequentialCheckExpectationsSoftAssert(t *testing.T) {
+	reporter := newTestReporter()
+	b := newTestSequentialBot(&storage.MemoryStorage{})
+	b.metricsReporter = []metrics.Reporter{reporter}
+	expect := models.ExpectSpec{"$response.code": {Type: "string", Value: "200"}}
+	resp := map[string]interface{}{"code": "500"}
+
+	err := b.checkExpectations(&models.Operation{Type: "request"}, "room.join", expect, resp, []byte(`{"code":"500"}`))
+	assert.IsType(t, &ExpectError{}, err)
+
+	err = b.checkExpectations(&models.Operation{Type: "request", SoftAssert: true}, "room.join", expect, resp, []byte(`{"code":"500"}`))
+	assert.NoError(t, err)
+	assert.Equal(t, float64(2), reporter.counts[constants.ExpectFailureCount+":room.join"])
+}
@@ -0,0 +1,321 @@
+package bot
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/topfreegames/pitaya-bot/constants"
+	"github.com/topfreegames/pitaya-bot/custom"
+	"github.com/topfreegames/pitaya-bot/models"
+)
+
+// knownOperations are the operation types the sequential bot is able to run
+var knownOperations = map[string]bool{
+	"request":  true,
+	"notify":   true,
+	"function": true,
+	"listen":   true,
+	"noPush":   true,
+	"loop":     true,
+	"if":       true,
+	"choice":   true,
+	"sleep":    true,
+}
+
+// knownFunctions are the internal functions a function operation may call
+var knownFunctions = map[string]bool{
+	"disconnect": true,
+	"connect":    true,
+	"reconnect":  true,
+}
+
+// specValidator walks a spec in the order its operations are written,
+// collecting every problem found instead of stopping at the first one
+type specValidator struct {
+	problems []error
+	// stored holds the keys saved by the operations visited so far
+	stored map[string]bool
+	// checkStore is false when a preRun function fills the storage, since
+	// the keys it provides are only known at runtime
+	checkStore bool
+	loopDepth  int
+}
+
+// ValidateSpec checks the spec without connecting to any server and returns
+// every problem found, each one prefixed by the path of the operation
+func ValidateSpec(spec *models.Spec) []error {
+	v := &specValidator{
+		stored:     map[string]bool{},
+		checkStore: spec.PreRun == nil,
+	}
+
+	if err := custom.ValidatePre(spec); err != nil {
+		v.report("preRun", err)
+	}
+	if err := custom.ValidatePost(spec); err != nil {
+		v.report("postRun", err)
+	}
+	if err := spec.GetExecutor().Validate(); err != nil {
+		v.report("executor", err)
+	}
+
+	v.validateOperations("sequentialOperations", spec.SequentialOperations)
+	return v.problems
+}
+
+func (v *specValidator) report(path string, err error) {
+	v.problems = append(v.problems, fmt.Errorf("%s: %s", path, err))
+}
+
+func (v *specValidator) validateOperations(path string, ops []*models.Operation) {
+	for idx, op := range ops {
+		v.validateOperation(fmt.Sprintf("%s[%d]", path, idx), op)
+	}
+}
+
+func (v *specValidator) validateOperation(path string, op *models.Operation) {
+	// unknown operators are reported by validateExpect for every entry
+	if err := op.ValidateFields(); err != nil && err != constants.ErrSpecInvalidOperator {
+		v.report(path, err)
+	}
+	if op == nil {
+		return
+	}
+
+	if !knownOperations[op.Type] {
+		v.report(path, fmt.Errorf("Unknown type: %s", op.Type))
+	}
+
+	switch op.Type {
+	case "function":
+		if !knownFunctions[op.URI] {
+			v.report(path, fmt.Errorf("Unknown function: %s", op.URI))
+		}
+	}
+
+	for name, params := range op.Args {
+		v.validateArg(fmt.Sprintf("%s.args.%s", path, name), params)
+	}
+
+	v.validateExpect(path+".expect", op.Expect, false)
+	v.validateExpect(path+".condition", op.Condition, true)
+
+	if op.Type == "loop" {
+		v.validateExpect(path+".while", op.While, true)
+		v.loopDepth++
+		v.validateOperations(path+".operations", op.Operations)
+		v.loopDepth--
+	} else {
+		v.validateOperations(path+".operations", op.Operations)
+	}
+	v.validateOperations(path+".else", op.Else)
+	for idx, choice := range op.Choices {
+		if choice != nil {
+			v.validateOperations(fmt.Sprintf("%s.choices[%d].operations", path, idx), choice.Operations)
+		}
+	}
+
+	for idx, route := range op.Routes {
+		if route != nil {
+			routePath := fmt.Sprintf("%s.routes[%d]", path, idx)
+			v.validateExpect(routePath+".expect", route.Expect, false)
+			v.validateStore(routePath+".store", route.Store)
+		}
+	}
+
+	v.validateStore(path+".store", op.Store)
+}
+
+// validateArg mirrors parseArg, reporting the problems it would fail with
+func (v *specValidator) validateArg(path string, params interface{}) {
+	p, ok := params.(map[string]interface{})
+	if !ok {
+		v.report(path, fmt.Errorf("arg must be an object with type and value, got %v", params))
+		return
+	}
+
+	paramType, ok := p["type"].(string)
+	if !ok {
+		v.report(path, fmt.Errorf("type is not available in arg"))
+		return
+	}
+	if !allowedTypes[paramType] {
+		v.report(path, fmt.Errorf("Unknown type %s", paramType))
+		return
+	}
+
+	if v.validateReference(path, p["value"]) || paramType == "any" {
+		return
+	}
+
+	switch value := p["value"].(type) {
+	case map[string]interface{}:
+		if paramType != "object" {
+			v.report(path, fmt.Errorf("%s type assertion failed for field: %v", paramType, value))
+			return
+		}
+		for key, elem := range value {
+			v.validateArg(fmt.Sprintf("%s.%s", path, key), elem)
+		}
+	case []interface{}:
+		if paramType != "array" {
+			v.report(path, fmt.Errorf("%s type assertion failed for field: %v", paramType, value))
+			return
+		}
+		for idx, elem := range value {
+			v.validateArg(fmt.Sprintf("%s[%d]", path, idx), elem)
+		}
+	default:
+		if _, err := assertType(value, paramType); err != nil {
+			v.report(path, err)
+		}
+	}
+}
+
+// validateReference checks values resolved by tryGetValue and returns true
+// if the value is one of them
+func (v *specValidator) validateReference(path string, value interface{}) bool {
+	val, ok := value.(string)
+	if !ok {
+		return false
+	}
+
+	switch {
+	case strings.HasPrefix(val, "$store"):
+		key := strings.TrimPrefix(val[len("$store"):], ".")
+		if v.checkStore && !v.stored[key] {
+			v.report(path, fmt.Errorf("%s is read before being stored", val))
+		}
+	case strings.HasPrefix(val, "$loop"):
+		if val != loopIndexKey {
+			v.report(path, fmt.Errorf("%s undefined", val))
+		} else if v.loopDepth == 0 {
+			v.report(path, fmt.Errorf("%s used outside of a loop", val))
+		}
+	case strings.HasPrefix(val, "$push"):
+		if val != pushRouteKey {
+			v.report(path, fmt.Errorf("%s undefined", val))
+		}
+	case strings.HasPrefix(val, "$util"):
+		if _, err := valueFromUtil(strings.TrimPrefix(val[len("$util"):], ".")); err != nil {
+			v.report(path, err)
+		}
+	default:
+		return false
+	}
+
+	return true
+}
+
+func (v *specValidator) validateExpect(path string, expect models.ExpectSpec, isCondition bool) {
+	for expr, entry := range expect {
+		entryPath := fmt.Sprintf("%s[%s]", path, expr)
+		prefix := "$response"
+		switch {
+		case isCondition && (expr == pushRouteKey || expr == loopIndexKey):
+			// values stored by the bot itself
+			prefix = expr
+		case isCondition && strings.HasPrefix(expr, "$store"):
+			// conditions may check whether a key was stored, so it is
+			// fine for them to read keys that might be missing
+			prefix = "$store"
+		}
+		if err := validateExpr(expr, prefix); err != nil {
+			v.report(entryPath, err)
+		}
+
+		if err := entry.Validate(); err != nil {
+			v.report(entryPath, err)
+			continue
+		}
+
+		operator := entry.GetOperator()
+		if operator == models.OperatorExists || operator == models.OperatorNotExists {
+			continue
+		}
+		if !allowedTypes[entry.Type] {
+			v.report(entryPath, fmt.Errorf("Unknown type %s", entry.Type))
+			continue
+		}
+		if v.validateReference(entryPath, entry.Value) {
+			continue
+		}
+
+		values := []interface{}{entry.Value}
+		if operator == models.OperatorIn {
+			list, ok := entry.Value.([]interface{})
+			if !ok {
+				v.report(entryPath, fmt.Errorf("in operator expects an array, got %v", entry.Value))
+				continue
+			}
+			values = list
+		}
+		for _, value := range values {
+			if _, err := assertType(value, entry.Type); err != nil {
+				v.report(entryPath, err)
+			}
+		}
+	}
+}
+
+func (v *specValidator) validateStore(path string, store models.StoreSpec) {
+	for name, entry := range store {
+		entryPath := fmt.Sprintf("%s.%s", path, name)
+		if !allowedTypes[entry.Type] {
+			v.report(entryPath, fmt.Errorf("Unknown type %s", entry.Type))
+		}
+		if strings.HasPrefix(entry.Value, "$response") {
+			if err := validateExpr(entry.Value, "$response"); err != nil {
+				v.report(entryPath, err)
+			}
+		}
+		v.stored[name] = true
+	}
+}
+
+// validateExpr checks the syntax of an expression like
+// $response.players[0]["name"], without evaluating it
+func validateExpr(expr, prefix string) error {
+	if !strings.HasPrefix(expr, prefix) {
+		return fmt.Errorf("expr %s must start with %s", expr, prefix)
+	}
+
+	path := expr[len(prefix):]
+	if path == "" {
+		return nil
+	}
+	if path[0] != '.' && path[0] != '[' {
+		return fmt.Errorf("malformed expr %s: expected . or [ after %s", expr, prefix)
+	}
+
+	isQuote, isBracket := false, false
+	for _, c := range path {
+		switch {
+		case c == '"':
+			isQuote = !isQuote
+		case isQuote:
+		case c == '[':
+			if isBracket {
+				return fmt.Errorf("malformed expr %s: nested [", expr)
+			}
+			isBracket = true
+		case c == ']':
+			if !isBracket {
+				return fmt.Errorf("malformed expr %s: unbalanced ]", expr)
+			}
+			isBracket = false
+		}
+	}
+
+	if isQuote {
+		return fmt.Errorf("malformed expr %s: unbalanced quote", expr)
+	}
+	if isBracket {
+		return fmt.Errorf("malformed expr %s: unbalanced [", expr)
+	}
+	if len(tokenSplit(path)) == 0 {
+		return fmt.Errorf("malformed expr %s: no field after %s", expr, prefix)
+	}
+
+	return nil
+}
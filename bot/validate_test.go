@@ -0,0 +1,161 @@
+package bot
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/topfreegames/pitaya-bot/models"
+)
+
+func TestValidateSpec(t *testing.T) {
+	login := &models.Operation{
+		Type: "request",
+		URI:  "connector.playerHandler.create",
+		Args: map[string]interface{}{
+			"name": map[string]interface{}{"type": "string", "value": "$util.uuid"},
+		},
+		Expect: models.ExpectSpec{"$response.code": {Type: "string", Value: "200"}},
+		Store:  models.StoreSpec{"playerId": {Type: "string", Value: "$response.player.id"}},
+	}
+
+	var validateSpecTable = map[string]struct {
+		spec   *models.Spec
+		errors []error
+	}{
+		"valid": {&models.Spec{SequentialOperations: []*models.Operation{
+			login,
+			{Type: "loop", Count: 2, Operations: []*models.Operation{
+				{Type: "notify", URI: "room.join", Args: map[string]interface{}{
+					"player": map[string]interface{}{"type": "string", "value": "$store.playerId"},
+					"round":  map[string]interface{}{"type": "int", "value": "$loop.index"},
+				}},
+			}},
+			{Type: "if", Condition: models.ExpectSpec{"$store.token": {Operator: "notExists"}}, Operations: []*models.Operation{
+				{Type: "listen", URI: "room.start", Timeout: 100},
+			}},
+		}}, nil},
+		"unknown_type_and_function": {&models.Spec{SequentialOperations: []*models.Operation{
+			{Type: "wait", URI: "room.start"},
+			{Type: "function", URI: "restart"},
+		}}, []error{
+			errors.New("sequentialOperations[0]: Unknown type: wait"),
+			errors.New("sequentialOperations[1]: Unknown function: restart"),
+		}},
+		"malformed_args": {&models.Spec{SequentialOperations: []*models.Operation{
+			{Type: "request", URI: "room.join", Args: map[string]interface{}{
+				"a": "room",
+				"b": map[string]interface{}{"value": "room"},
+				"c": map[string]interface{}{"type": "number", "value": 1},
+				"d": map[string]interface{}{"type": "int", "value": "one"},
+				"e": map[string]interface{}{"type": "object", "value": []interface{}{}},
+				"f": map[string]interface{}{"type": "string", "value": "$util.now"},
+			}},
+		}}, []error{
+			errors.New("sequentialOperations[0].args.a: arg must be an object with type and value, got room"),
+			errors.New("sequentialOperations[0].args.b: type is not available in arg"),
+			errors.New("sequentialOperations[0].args.c: Unknown type number"),
+			errors.New("sequentialOperations[0].args.d: int type assertion failed for field: one"),
+			errors.New("sequentialOperations[0].args.e: object type assertion failed for field: []"),
+			errors.New("sequentialOperations[0].args.f: util.now undefined"),
+		}},
+		"store_read_before_written": {&models.Spec{SequentialOperations: []*models.Operation{
+			{Type: "request", URI: "room.join", Args: map[string]interface{}{
+				"player": map[string]interface{}{"type": "string", "value": "$store.playerId"},
+			}},
+			login,
+		}}, []error{
+			errors.New("sequentialOperations[0].args.player: $store.playerId is read before being stored"),
+		}},
+		"store_from_pre_run": {&models.Spec{
+			PreRun: &models.InitialDefinitions{Function: "redis"},
+			SequentialOperations: []*models.Operation{
+				{Type: "request", URI: "room.join", Args: map[string]interface{}{
+					"player": map[string]interface{}{"type": "string", "value": "$store.playerId"},
+				}},
+			},
+		}, nil},
+		"loop_index_outside_loop": {&models.Spec{SequentialOperations: []*models.Operation{
+			{Type: "request", URI: "room.join", Args: map[string]interface{}{
+				"round": map[string]interface{}{"type": "int", "value": "$loop.index"},
+			}},
+		}}, []error{
+			errors.New("sequentialOperations[0].args.round: $loop.index used outside of a loop"),
+		}},
+		"bad_expressions": {&models.Spec{SequentialOperations: []*models.Operation{
+			{Type: "request", URI: "room.join", Expect: models.ExpectSpec{"$respons.code": {Type: "string", Value: "200"}}},
+			{Type: "request", URI: "room.join", Expect: models.ExpectSpec{"$response.players[0": {Type: "string", Value: "200"}}},
+			{Type: "request", URI: "room.join", Store: models.StoreSpec{"code": {Type: "string", Value: "$responsecode"}}},
+		}}, []error{
+			errors.New("sequentialOperations[0].expect[$respons.code]: expr $respons.code must start with $response"),
+			errors.New("sequentialOperations[1].expect[$response.players[0]: malformed expr $response.players[0: unbalanced ["),
+			errors.New("sequentialOperations[2].store.code: malformed expr $responsecode: expected . or [ after $response"),
+		}},
+		"bad_expectations": {&models.Spec{SequentialOperations: []*models.Operation{
+			{Type: "request", URI: "room.join", Expect: models.ExpectSpec{"$response.code": {Type: "string", Value: "200", Operator: "between"}}},
+			{Type: "request", URI: "room.join", Expect: models.ExpectSpec{"$response.code": {Type: "int", Value: "200"}}},
+		}}, []error{
+			errors.New("sequentialOperations[0].expect[$response.code]: invalid spec: Operator"),
+			errors.New("sequentialOperations[1].expect[$response.code]: int type assertion failed for field: 200"),
+		}},
+		"listen_without_timeout": {&models.Spec{SequentialOperations: []*models.Operation{
+			{Type: "choice", URI: "matchmaking", Choices: []*models.Choice{
+				{Weight: 1, Operations: []*models.Operation{{Type: "listen", URI: "room.start"}}},
+			}},
+		}}, []error{
+			errors.New("sequentialOperations[0].choices[0].operations[0]: invalid spec: Timeout"),
+		}},
+		"listen_routes": {&models.Spec{SequentialOperations: []*models.Operation{
+			{Type: "listen", Timeout: 100, Routes: []*models.PushRoute{
+				{URI: "match.found", Store: models.StoreSpec{"roomId": {Type: "string", Value: "$response.room.id"}}},
+				{URI: "match.cancelled", Expect: models.ExpectSpec{"$response.reason": {Type: "int", Value: "timeout"}}},
+			}},
+			{Type: "if", Condition: models.ExpectSpec{"$push.route": {Type: "string", Value: "match.found"}}, Operations: []*models.Operation{
+				{Type: "request", URI: "room.join", Args: map[string]interface{}{
+					"room":  map[string]interface{}{"type": "string", "value": "$store.roomId"},
+					"route": map[string]interface{}{"type": "string", "value": "$push.id"},
+				}},
+			}},
+		}}, []error{
+			errors.New("sequentialOperations[0].routes[1].expect[$response.reason]: int type assertion failed for field: timeout"),
+			errors.New("sequentialOperations[1].operations[0].args.route: $push.id undefined"),
+		}},
+		"unknown_pre_and_post_run": {&models.Spec{
+			PreRun:  &models.InitialDefinitions{Function: "mysql"},
+			PostRun: &models.FinalDefinitions{Function: "mysql"},
+		}, []error{
+			errors.New("preRun: Unknown preRun function: mysql"),
+			errors.New("postRun: Unknown postRun function: mysql"),
+		}},
+	}
+
+	for name, table := range validateSpecTable {
+		t.Run(name, func(t *testing.T) {
+			assert.ElementsMatch(t, table.errors, ValidateSpec(table.spec))
+		})
+	}
+}
+
+func TestValidateExpr(t *testing.T) {
+	var validateExprTable = map[string]struct {
+		expr string
+		err  error
+	}{
+		"root":           {"$response", nil},
+		"field":          {"$response.player.name", nil},
+		"index":          {"$response.players[0].name", nil},
+		"quoted":         {`$response["player.name"]`, nil},
+		"wrong_prefix":   {"$store.player", errors.New("expr $store.player must start with $response")},
+		"no_separator":   {"$responseplayer", errors.New("malformed expr $responseplayer: expected . or [ after $response")},
+		"unbalanced_end": {"$response.players]", errors.New("malformed expr $response.players]: unbalanced ]")},
+		"nested":         {"$response[[0]]", errors.New("malformed expr $response[[0]]: nested [")},
+		"open_quote":     {`$response["name]`, errors.New(`malformed expr $response["name]: unbalanced quote`)},
+		"empty_field":    {"$response.", errors.New("malformed expr $response.: no field after $response")},
+	}
+
+	for name, table := range validateExprTable {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, table.err, validateExpr(table.expr, "$response"))
+		})
+	}
+}
@@ -19,6 +19,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"math"
 	"os"
 	"strings"
 	"time"
@@ -79,7 +80,11 @@ func parseArg(arg interface{}) (map[string]interface{}, error) {
 	case int:
 		val["type"] = "int"
 	case float64:
-		val["type"] = "int"
+		if t == math.Trunc(t) {
+			val["type"] = "int"
+		} else {
+			val["type"] = "float"
+		}
 	case bool:
 		val["type"] = "bool"
 	case map[string]interface{}:
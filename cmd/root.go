@@ -28,6 +28,7 @@ import (
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+	"github.com/topfreegames/pitaya-bot/metrics"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/client-go/util/homedir"
 )
@@ -99,6 +100,15 @@ func fillDefaultValues(config *viper.Viper) {
 	defaultsMap := map[string]interface{}{
 		"game":                                "",
 		"prometheus.port":                     9191,
+		"prometheus.pushgateway.url":          "",
+		"prometheus.pushgateway.job":          "pitaya-bot",
+		"prometheus.pushgateway.runId":        "",
+		"prometheus.pushgateway.interval":     "10s",
+		"prometheus.histogram.buckets":        metrics.DefaultBuckets,
+		"metrics.reporters":                   []string{"prometheus"},
+		"metrics.statsd.host":                 "localhost:8125",
+		"metrics.statsd.prefix":               "pitaya_bot.",
+		"metrics.statsd.dogstatsd":            true,
 		"server.host":                         "localhost",
 		"server.tls":                          "false",
 		"server.serializer":                   "json",
@@ -118,7 +128,16 @@ func fillDefaultValues(config *viper.Viper) {
 		"manager.wait":                        "1s",
 		"bot.operation.maxSleep":              "500ms",
 		"bot.operation.stopOnError":           false,
+		"bot.operation.maxLoopIterations":     1000,
+		"bot.operation.seed":                  0,
+		"bot.operation.maxRestarts":           3,
+		"bot.shutdown.gracePeriod":            "30s",
+		"bot.results.path":                    "",
+		"bot.results.format":                  "csv",
+		"bot.results.bufferSize":              10000,
 		"bot.spec.parallelism":                1,
+		"bot.push.queueSize":                  100,
+		"bot.push.dropPolicy":                 "oldest",
 		"custom.redis.pre.url":                "redis://localhost:9010",
 		"custom.redis.pre.connectionTimeout":  10,
 		"custom.redis.pre.script":             "",
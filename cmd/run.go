@@ -35,6 +35,7 @@ var (
 	testDuration    time.Duration
 	reportMetrics   bool
 	deleteBeforeRun bool
+	summaryOut      string
 )
 
 // runCmd represents the run command
@@ -60,7 +61,7 @@ var runCmd = &cobra.Command{
 			launcher.LaunchDeleteAll(config, logger)
 		default:
 			app := state.NewApp(config, reportMetrics)
-			launcher.Launch(app, config, specsDirectory, testDuration.Seconds(), reportMetrics, logger)
+			launcher.Launch(app, config, specsDirectory, testDuration.Seconds(), reportMetrics, summaryOut, logger)
 		}
 	},
 }
@@ -75,4 +76,5 @@ func init() {
 	runCmd.PersistentFlags().BoolVar(&reportMetrics, "report-metrics", false, "Should metrics be reported")
 	runCmd.PersistentFlags().StringVarP(&pitayaBotType, "pitaya-bot-type", "t", "local", "Pitaya-Bot Type which will be run")
 	runCmd.PersistentFlags().BoolVar(&deleteBeforeRun, "delete", false, "Delete all before run")
+	runCmd.PersistentFlags().StringVar(&summaryOut, "summary-out", "", "File to write the end-of-run summary as JSON")
 }
@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/topfreegames/pitaya-bot/bot"
+	"github.com/topfreegames/pitaya-bot/launcher"
+)
+
+var validateDirectory string
+
+// validateCmd represents the validate command
+var validateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Validates the specs",
+	Long: `Validates the specs without connecting to any server, reporting every
+problem found. Exits with a non-zero code if any spec is invalid.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		logger := getLogger()
+
+		specs, err := launcher.GetSpecs(validateDirectory)
+		if err != nil {
+			logger.WithError(err).Error("Failed to read specs")
+			os.Exit(1)
+		}
+
+		problems := 0
+		if config != nil {
+			if _, err := launcher.GetThresholds(config); err != nil {
+				logger.WithError(err).Error("Invalid thresholds")
+				problems++
+			}
+			if _, err := launcher.GetReportOutputs(config); err != nil {
+				logger.WithError(err).Error("Invalid reports")
+				problems++
+			}
+		}
+
+		for _, spec := range specs {
+			errs := bot.ValidateSpec(spec)
+			for _, err := range errs {
+				logger.WithFields(logrus.Fields{"spec": spec.Name}).Error(err)
+			}
+			problems += len(errs)
+		}
+
+		if problems > 0 {
+			logger.Errorf("Found %d problems in %d specs", problems, len(specs))
+			os.Exit(1)
+		}
+		logger.Infof("All %d specs are valid", len(specs))
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(validateCmd)
+
+	validateCmd.Flags().StringVarP(&validateDirectory, "dir", "d", "./specs/", "Specs to validate")
+}
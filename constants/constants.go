@@ -4,9 +4,85 @@ var (
 	// ResponseTime reports the response time of handlers and rpc
 	ResponseTime = "response_time_ms"
 
-	// ResponseTimeHistogram ...
+	// ResponseTimeHistogram reports the response time of requests in buckets
 	ResponseTimeHistogram = "response_time_histogram_ms"
 
 	// ErrorCount reports the number of requests that returned unexpected errors
 	ErrorCount = "error_count"
+
+	// ChoiceCount reports the number of times each branch of a choice operation was taken
+	ChoiceCount = "choice_count"
+
+	// FailureCount reports the number of operation failures that didn't stop the bot
+	FailureCount = "failure_count"
+
+	// DroppedIterationCount reports the number of bots an executor couldn't start because it was at max concurrency
+	DroppedIterationCount = "dropped_iteration_count"
+
+	// TargetBots reports the number of bots a ramping executor is aiming for
+	TargetBots = "target_bots"
+
+	// ActiveBots reports the number of bots running a spec
+	ActiveBots = "active_bots"
+
+	// RetryCount reports the number of times a failed request was retried
+	RetryCount = "retry_count"
+
+	// PushDroppedCount reports the number of pushes dropped because their route queue was full
+	PushDroppedCount = "push_dropped_count"
+
+	// PushUnconsumedCount reports the number of pushes still queued when the client disconnected
+	PushUnconsumedCount = "push_unconsumed_count"
+
+	// PushWaitTime reports the time listen operations waited for a push
+	PushWaitTime = "push_wait_time_ms"
+
+	// PushTimeoutCount reports the number of listen operations that timed out waiting for a push
+	PushTimeoutCount = "push_timeout_count"
+
+	// PushWaitTimeHistogram reports the time listen operations waited for a push in buckets
+	PushWaitTimeHistogram = "push_wait_time_histogram_ms"
+
+	// NotifyCount reports the number of notifies sent
+	NotifyCount = "notify_count"
+
+	// NotifyErrorCount reports the number of notifies that failed to be sent
+	NotifyErrorCount = "notify_error_count"
+
+	// RequestTimeoutCount reports the number of requests that timed out
+	RequestTimeoutCount = "request_timeout_count"
+
+	// ExpectFailureCount reports the number of responses and pushes that failed their expectations
+	ExpectFailureCount = "expect_failure_count"
+
+	// BytesSent reports the size of the requests and notifies sent
+	BytesSent = "bytes_sent"
+
+	// BytesReceived reports the size of the responses and pushes received
+	BytesReceived = "bytes_received"
+
+	// ConnectionAttemptCount reports the number of times bots tried to connect to the server
+	ConnectionAttemptCount = "connection_attempt_count"
+
+	// ConnectionFailureCount reports the number of times bots failed to connect to the server
+	ConnectionFailureCount = "connection_failure_count"
+
+	// ConnectionTime reports the time bots took to connect to the server
+	ConnectionTime = "connection_time_ms"
+
+	// IterationStartedCount reports the number of times bots started running a spec
+	IterationStartedCount = "iteration_started_count"
+
+	// IterationCompletedCount reports the number of times bots finished running a spec
+	IterationCompletedCount = "iteration_completed_count"
+
+	// IterationFailedCount reports the number of times bots failed running a spec
+	IterationFailedCount = "iteration_failed_count"
 )
+
+// ExitCodeInterrupted is the exit code of a run stopped by SIGINT or SIGTERM
+const ExitCodeInterrupted = 130
+
+// ExitCodeThresholdsFailed is the exit code of a run that crossed any of its
+// thresholds
+const ExitCodeThresholdsFailed = 99
@@ -7,11 +7,24 @@ var (
 	ErrStorageKeyNotFound  = errors.New("storage key not found")
 	ErrStorageTypeNotFound = errors.New("storage type not found")
 	ErrMalformedObject     = errors.New("malformed object type argument")
+	ErrBotAborted          = errors.New("bot aborted")
 )
 
 // Errors that are related to a spec
 var (
-	ErrSpecInvalidNil   = errors.New("invalid spec: nil")
-	ErrSpecInvalidType  = errors.New("invalid spec: Type")
-	ErrSpecInvalidURI   = errors.New("invalid spec: URI")
+	ErrSpecInvalidNil        = errors.New("invalid spec: nil")
+	ErrSpecInvalidType       = errors.New("invalid spec: Type")
+	ErrSpecInvalidURI        = errors.New("invalid spec: URI")
+	ErrSpecInvalidOperator   = errors.New("invalid spec: Operator")
+	ErrSpecInvalidLoop       = errors.New("invalid spec: loop must have Count or While")
+	ErrSpecInvalidOperations = errors.New("invalid spec: Operations")
+	ErrSpecInvalidCondition  = errors.New("invalid spec: Condition")
+	ErrSpecInvalidChoices    = errors.New("invalid spec: Choices")
+	ErrSpecInvalidDelay      = errors.New("invalid spec: Delay")
+	ErrSpecInvalidRoutes     = errors.New("invalid spec: Routes")
+	ErrSpecInvalidTimeout    = errors.New("invalid spec: Timeout")
+	ErrSpecInvalidRetry      = errors.New("invalid spec: Retry")
+	ErrSpecInvalidOnFailure  = errors.New("invalid spec: OnFailure")
+	ErrSpecInvalidExecutor   = errors.New("invalid spec: Executor")
+	ErrSpecInvalidStages     = errors.New("invalid spec: Stages")
 )
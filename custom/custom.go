@@ -1,6 +1,8 @@
 package custom
 
 import (
+	"fmt"
+
 	"github.com/topfreegames/pitaya-bot/custom/redis"
 	"github.com/topfreegames/pitaya-bot/models"
 	"github.com/topfreegames/pitaya-bot/storage"
@@ -69,3 +71,29 @@ func GetPost(config *viper.Viper, spec *models.Spec) (PostOperation, map[string]
 	}
 	return &DummyPost{}, map[string]interface{}{}
 }
+
+// ValidatePre returns an error if the spec preRun function is unknown
+func ValidatePre(spec *models.Spec) error {
+	if spec.PreRun == nil {
+		return nil
+	}
+
+	switch spec.PreRun.Function {
+	case PreRunFunctionRedis:
+		return nil
+	}
+	return fmt.Errorf("Unknown preRun function: %s", spec.PreRun.Function)
+}
+
+// ValidatePost returns an error if the spec postRun function is unknown
+func ValidatePost(spec *models.Spec) error {
+	if spec.PostRun == nil {
+		return nil
+	}
+
+	switch spec.PostRun.Function {
+	case PostRunFunctionRedis:
+		return nil
+	}
+	return fmt.Errorf("Unknown postRun function: %s", spec.PostRun.Function)
+}
@@ -36,6 +36,7 @@ require (
 	google.golang.org/appengine v1.3.0 // indirect
 	google.golang.org/protobuf v1.31.0 // indirect
 	gopkg.in/inf.v0 v0.9.1 // indirect
+	gopkg.in/yaml.v3 v3.0.1
 	k8s.io/api v0.0.0-20181130031204-d04500c8c3dd
 	k8s.io/apimachinery v0.0.0-20181215012845-4d029f033399
 	k8s.io/client-go v10.0.0+incompatible
@@ -1,6 +1,7 @@
 package kubernetes
 
 import (
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"path/filepath"
@@ -34,7 +35,8 @@ func CreateManagerPod(logger logrus.FieldLogger, clientset kubernetes.Interface,
 
 	binData := make(map[string][]byte, len(specs))
 	for _, spec := range specs {
-		specBinary, err := ioutil.ReadFile(spec.Name)
+		// specs are sent with their includes already resolved
+		specBinary, err := json.Marshal(spec)
 		if err != nil {
 			logger.Fatal(err)
 		}
@@ -97,7 +99,7 @@ func deployJobs(logger logrus.FieldLogger, clientset kubernetes.Interface, confi
 	createConfigMap(configName, app, map[string][]byte{"config.yaml": configBinary}, logger, clientset, config)
 
 	for _, spec := range specs {
-		specBinary, err := ioutil.ReadFile(spec.Name)
+		specBinary, err := json.Marshal(spec)
 		if err != nil {
 			logger.Fatal(err)
 		}
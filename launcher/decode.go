@@ -0,0 +1,232 @@
+package launcher
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	yaml "gopkg.in/yaml.v3"
+)
+
+// isYAML returns true if the file must be decoded as YAML instead of JSON
+func isYAML(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return ext == ".yaml" || ext == ".yml"
+}
+
+// unmarshalFile decodes the content of a JSON or YAML file into v. YAML
+// documents are converted to JSON first, so v only needs json tags
+func unmarshalFile(path string, raw []byte, v interface{}) error {
+	if isYAML(path) {
+		return unmarshalYAML(path, raw, v)
+	}
+
+	if err := json.Unmarshal(raw, v); err != nil {
+		return jsonError(path, raw, err)
+	}
+	return nil
+}
+
+// unmarshalYAML converts the YAML document to JSON and decodes it into v,
+// failing with the line and column of the YAML node that couldn't be decoded
+func unmarshalYAML(path string, raw []byte, v interface{}) error {
+	var document yaml.Node
+	if err := yaml.Unmarshal(raw, &document); err != nil {
+		return fmt.Errorf("%s: %s", path, err)
+	}
+
+	c := &yamlConverter{}
+	if err := c.convert(&document); err != nil {
+		return fmt.Errorf("%s:%d:%d: %s", path, c.failed.Line, c.failed.Column, err)
+	}
+
+	converted := c.buf.Bytes()
+	if err := json.Unmarshal(converted, v); err != nil {
+		var offset int64
+		switch e := err.(type) {
+		case *json.SyntaxError:
+			offset = e.Offset
+		case *json.UnmarshalTypeError:
+			offset = e.Offset
+		default:
+			return fmt.Errorf("%s: %s", path, err)
+		}
+
+		node := c.nodeAt(offset)
+		return fmt.Errorf("%s:%d:%d: %s", path, node.Line, node.Column, strings.TrimPrefix(err.Error(), "json: "))
+	}
+	return nil
+}
+
+// yamlConverter writes YAML nodes as JSON, keeping the offset in the JSON
+// where each node starts, in increasing order
+type yamlConverter struct {
+	buf     bytes.Buffer
+	offsets []int
+	nodes   []*yaml.Node
+	// failed is the node that couldn't be converted
+	failed *yaml.Node
+}
+
+func (c *yamlConverter) convert(node *yaml.Node) error {
+	c.offsets = append(c.offsets, c.buf.Len())
+	c.nodes = append(c.nodes, node)
+
+	switch node.Kind {
+	case yaml.DocumentNode:
+		if len(node.Content) == 0 {
+			c.buf.WriteString("null")
+			return nil
+		}
+		return c.convert(node.Content[0])
+	case yaml.AliasNode:
+		return c.convert(node.Alias)
+	case yaml.SequenceNode:
+		c.buf.WriteByte('[')
+		for idx, item := range node.Content {
+			if idx > 0 {
+				c.buf.WriteByte(',')
+			}
+			if err := c.convert(item); err != nil {
+				return err
+			}
+		}
+		c.buf.WriteByte(']')
+		return nil
+	case yaml.MappingNode:
+		c.buf.WriteByte('{')
+		pairs, err := c.mappingPairs(node)
+		if err != nil {
+			return err
+		}
+		for idx := 0; idx < len(pairs); idx += 2 {
+			if idx > 0 {
+				c.buf.WriteByte(',')
+			}
+			if err := c.convertKey(pairs[idx]); err != nil {
+				return err
+			}
+			c.buf.WriteByte(':')
+			if err := c.convert(pairs[idx+1]); err != nil {
+				return err
+			}
+		}
+		c.buf.WriteByte('}')
+		return nil
+	}
+
+	return c.convertScalar(node)
+}
+
+// mappingPairs returns the keys and values of the mapping, preceded by the
+// ones of the mappings merged with <<, so the mapping's own keys override them
+func (c *yamlConverter) mappingPairs(node *yaml.Node) ([]*yaml.Node, error) {
+	var merged, own []*yaml.Node
+	for idx := 0; idx+1 < len(node.Content); idx += 2 {
+		key, value := node.Content[idx], node.Content[idx+1]
+		if key.Tag != "!!merge" {
+			own = append(own, key, value)
+			continue
+		}
+
+		sources := []*yaml.Node{value}
+		if value.Kind == yaml.SequenceNode {
+			sources = value.Content
+		}
+		for _, source := range sources {
+			if source.Kind == yaml.AliasNode {
+				source = source.Alias
+			}
+			if source.Kind != yaml.MappingNode {
+				c.failed = source
+				return nil, errors.New("only mappings can be merged")
+			}
+			pairs, err := c.mappingPairs(source)
+			if err != nil {
+				return nil, err
+			}
+			merged = append(merged, pairs...)
+		}
+	}
+	return append(merged, own...), nil
+}
+
+// convertKey writes a mapping key as a JSON string, keys such as numbers are
+// written as they appear in the document
+func (c *yamlConverter) convertKey(key *yaml.Node) error {
+	if key.Kind == yaml.AliasNode {
+		key = key.Alias
+	}
+	if key.Kind != yaml.ScalarNode {
+		c.failed = key
+		return errors.New("mapping keys must be scalars")
+	}
+
+	encoded, _ := json.Marshal(key.Value)
+	c.buf.Write(encoded)
+	return nil
+}
+
+func (c *yamlConverter) convertScalar(node *yaml.Node) error {
+	var value interface{}
+	if err := node.Decode(&value); err != nil {
+		c.failed = node
+		return err
+	}
+
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		c.failed = node
+		return fmt.Errorf("cannot convert %s to JSON", node.Value)
+	}
+	c.buf.Write(encoded)
+	return nil
+}
+
+// nodeAt returns the last node that starts before offset, which is where
+// the JSON decoder stopped
+func (c *yamlConverter) nodeAt(offset int64) *yaml.Node {
+	idx := sort.Search(len(c.offsets), func(i int) bool {
+		return int64(c.offsets[i]) >= offset
+	})
+	if idx > 0 {
+		idx--
+	}
+	return c.nodes[idx]
+}
+
+// jsonError adds the line and column where decoding failed to the error
+func jsonError(path string, raw []byte, err error) error {
+	var offset int64
+	switch e := err.(type) {
+	case *json.SyntaxError:
+		offset = e.Offset
+	case *json.UnmarshalTypeError:
+		offset = e.Offset
+	default:
+		return fmt.Errorf("%s: %s", path, err)
+	}
+
+	line, column := position(raw, offset)
+	return fmt.Errorf("%s:%d:%d: %s", path, line, column, err)
+}
+
+// position returns the 1-based line and column of the last byte read by the
+// decoder, given the number of bytes it read
+func position(raw []byte, offset int64) (int, int) {
+	if offset > int64(len(raw)) {
+		offset = int64(len(raw))
+	}
+	if offset > 0 {
+		offset--
+	}
+
+	before := raw[:offset]
+	line := bytes.Count(before, []byte("\n")) + 1
+	column := len(before) - bytes.LastIndexByte(before, '\n')
+	return line, column
+}
@@ -0,0 +1,97 @@
+package launcher
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetSpecsYAML(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"spec.json": `{
+			"numberOfInstances": 2,
+			"sequentialOperations": [
+				{"type": "request", "uri": "connector.playerHandler.create", "timeout": 100, "expect": {"$response.code": {"type": "string", "value": "200"}}},
+				{"type": "request", "uri": "connector.playerHandler.create", "timeout": 100, "expect": {"$response.code": {"type": "string", "value": "200"}}},
+				{"type": "include", "uri": "login.yml"}
+			]
+		}`,
+		"spec.yaml": `
+numberOfInstances: 2
+# operations share the same expectations through an anchor
+sequentialOperations:
+  - &create
+    type: request
+    uri: connector.playerHandler.create
+    timeout: 100
+    expect:
+      $response.code:
+        type: string
+        value: "200"
+  - *create
+  - type: include
+    uri: login.yml
+`,
+		"login.yml": `
+operations:
+  - type: function
+    uri: reconnect
+`,
+	})
+	defer os.RemoveAll(dir)
+
+	specs, err := GetSpecs(dir)
+	assert.NoError(t, err)
+	assert.Len(t, specs, 2)
+	assert.Equal(t, specs[0].SequentialOperations, specs[1].SequentialOperations)
+	assert.Equal(t, specs[0].NumberOfInstances, specs[1].NumberOfInstances)
+}
+
+func TestGetSpecsErrorPosition(t *testing.T) {
+	var errorPositionTable = map[string]struct {
+		name    string
+		content string
+		err     string
+	}{
+		"json_syntax": {"spec.json", "{\n  \"numberOfInstances\": 1,\n  \"sequentialOperations\": [}\n}", "spec.json:3:28: invalid character '}'"},
+		"json_type":   {"spec.json", "{\n  \"numberOfInstances\": \"one\"\n}", "spec.json:2:28: json: cannot unmarshal string"},
+		"yaml_syntax": {"spec.yaml", "numberOfInstances: 1\nsequentialOperations:\n  - type: request\n   uri: route\n", "spec.yaml: yaml: line 2: did not find expected '-' indicator"},
+		"yaml_type":   {"spec.yaml", "numberOfInstances: one\n", "spec.yaml:1:20: cannot unmarshal string"},
+		"yaml_nested": {"spec.yaml", "numberOfInstances: 1\nsequentialOperations:\n  - type: request\n    timeout: [1]\n", "spec.yaml:4:14: cannot unmarshal array"},
+		"yaml_key":    {"spec.yaml", "numberOfInstances: 1\n? [a, b]\n: c\n", "spec.yaml:2:3: mapping keys must be scalars"},
+	}
+
+	for name, table := range errorPositionTable {
+		t.Run(name, func(t *testing.T) {
+			dir := writeFiles(t, map[string]string{table.name: table.content})
+			defer os.RemoveAll(dir)
+
+			_, err := GetSpecs(dir)
+			assert.Error(t, err)
+			assert.Contains(t, err.Error(), table.err)
+		})
+	}
+}
+
+func TestUnmarshalFileYAMLKeys(t *testing.T) {
+	raw := []byte(`
+base: &base
+  room: lobby
+  size: 2
+args:
+  <<: *base
+  size: 4
+  1: one
+  true: yes
+`)
+
+	var content map[string]map[string]interface{}
+	assert.NoError(t, unmarshalFile("spec.yaml", raw, &content))
+	assert.Equal(t, map[string]interface{}{
+		"room": "lobby",
+		"size": float64(4),
+		"1":    "one",
+		"true": "yes",
+	}, content["args"])
+}
@@ -0,0 +1,264 @@
+package launcher
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/topfreegames/pitaya-bot/models"
+)
+
+// botFunc runs the spec once with a new bot with the given id
+type botFunc func(id int) error
+
+// errorCollector gathers the errors of bots running concurrently
+type errorCollector struct {
+	mutex  sync.Mutex
+	errs   []error
+	failed chan struct{}
+}
+
+func newErrorCollector() *errorCollector {
+	return &errorCollector{failed: make(chan struct{})}
+}
+
+func (c *errorCollector) add(err error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if len(c.errs) == 0 {
+		close(c.failed)
+	}
+	c.errs = append(c.errs, err)
+}
+
+func (c *errorCollector) errors() []error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.errs
+}
+
+// runConstantArrivalRate starts bots at the executor rate until duration
+// expires or stop is closed, without waiting for the running ones to finish.
+// When maxConcurrency bots are already running, the bot is not started and
+// dropped is called
+func runConstantArrivalRate(executor *models.Executor, duration time.Duration, stopOnError bool, stop <-chan struct{}, run botFunc, dropped func()) []error {
+	var (
+		wg        sync.WaitGroup
+		collector = newErrorCollector()
+		slots     = make(chan struct{}, executor.MaxConcurrency)
+		ticker    = time.NewTicker(time.Duration(float64(time.Second) / executor.Rate))
+		deadline  = time.NewTimer(duration)
+	)
+	defer ticker.Stop()
+	defer deadline.Stop()
+
+	var failed <-chan struct{}
+	if stopOnError {
+		failed = collector.failed
+	}
+
+	for id := 0; ; {
+		select {
+		case <-deadline.C:
+			wg.Wait()
+			return collector.errors()
+		case <-failed:
+			wg.Wait()
+			return collector.errors()
+		case <-stop:
+			wg.Wait()
+			return collector.errors()
+		case <-ticker.C:
+		}
+
+		select {
+		case slots <- struct{}{}:
+			wg.Add(1)
+			go func(id int) {
+				defer wg.Done()
+				defer func() { <-slots }()
+				if err := run(id); err != nil {
+					collector.add(err)
+				}
+			}(id)
+			id++
+		default:
+			dropped()
+		}
+	}
+}
+
+// loopingBot runs the spec repeatedly with the same bot id until it is
+// stopped. A stopped bot finishes the iteration it is running
+type loopingBot struct {
+	stop chan struct{}
+}
+
+const (
+	// minLoopBackoff is the pause of a looping bot after an iteration fails,
+	// doubled on each consecutive failure up to maxLoopBackoff
+	minLoopBackoff = 100 * time.Millisecond
+	maxLoopBackoff = 5 * time.Second
+)
+
+// startLoopingBot runs the spec with the bot id at most iterations times, or
+// until stopped if iterations is not positive. A failed iteration is
+// followed by a backoff, so bots failing right away don't spin
+func startLoopingBot(id, iterations int, run botFunc, collector *errorCollector, wg *sync.WaitGroup) *loopingBot {
+	bot := &loopingBot{stop: make(chan struct{})}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		backoff := minLoopBackoff
+		for i := 0; iterations <= 0 || i < iterations; i++ {
+			select {
+			case <-bot.stop:
+				return
+			default:
+			}
+
+			err := run(id)
+			if err == nil {
+				backoff = minLoopBackoff
+				continue
+			}
+			collector.add(err)
+			if !bot.pause(backoff) {
+				return
+			}
+			if backoff *= 2; backoff > maxLoopBackoff {
+				backoff = maxLoopBackoff
+			}
+		}
+	}()
+	return bot
+}
+
+// pause waits for d, returning false if the bot is stopped before it is over
+func (b *loopingBot) pause(d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-b.stop:
+		return false
+	}
+}
+
+func (b *loopingBot) Stop() {
+	close(b.stop)
+}
+
+// runConstantBots runs instances looping bots, each one independently of the
+// others, until duration expires, stop is closed or all of them ran their
+// iterations
+func runConstantBots(instances int, executor *models.Executor, duration time.Duration, stopOnError bool, stop <-chan struct{}, run botFunc) []error {
+	var (
+		wg        sync.WaitGroup
+		collector = newErrorCollector()
+		bots      = make([]*loopingBot, instances)
+		done      = make(chan struct{})
+		deadline  = time.NewTimer(duration)
+	)
+	defer deadline.Stop()
+
+	for id := range bots {
+		bots[id] = startLoopingBot(id, executor.Iterations, run, collector, &wg)
+	}
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	var failed <-chan struct{}
+	if stopOnError {
+		failed = collector.failed
+	}
+
+	select {
+	case <-done:
+	case <-deadline.C:
+	case <-failed:
+	case <-stop:
+	}
+
+	for _, bot := range bots {
+		bot.Stop()
+	}
+	<-done
+	return collector.errors()
+}
+
+// stageTarget returns the number of bots the stages aim for once elapsed has
+// passed since they started, and false if the stages are over
+func stageTarget(stages []*models.Stage, elapsed time.Duration) (int, bool) {
+	from := 0
+	for _, stage := range stages {
+		d, _ := stage.GetDuration()
+		if elapsed < d {
+			progress := float64(elapsed) / float64(d)
+			return from + int(math.Round(float64(stage.Target-from)*progress)), true
+		}
+		elapsed -= d
+		from = stage.Target
+	}
+
+	return from, false
+}
+
+// runRampingBots starts and stops looping bots every interval so that the
+// number of bots running follows the executor stages, until they are over or
+// stop is closed. report is called with the target after each change, the
+// bots actually running are reported by the runner
+func runRampingBots(executor *models.Executor, interval time.Duration, stopOnError bool, stop <-chan struct{}, run botFunc, report func(target int)) []error {
+	var (
+		wg        sync.WaitGroup
+		bots      []*loopingBot
+		collector = newErrorCollector()
+		ticker    = time.NewTicker(interval)
+		start     = time.Now()
+	)
+	defer ticker.Stop()
+
+	var failed <-chan struct{}
+	if stopOnError {
+		failed = collector.failed
+	}
+
+loop:
+	for id := 0; ; {
+		target, ok := stageTarget(executor.Stages, time.Since(start))
+		if !ok {
+			break
+		}
+
+		for len(bots) < target {
+			bots = append(bots, startLoopingBot(id, 0, run, collector, &wg))
+			id++
+		}
+		for len(bots) > target {
+			bots[len(bots)-1].Stop()
+			bots = bots[:len(bots)-1]
+		}
+		report(target)
+
+		select {
+		case <-ticker.C:
+		case <-failed:
+			break loop
+		case <-stop:
+			break loop
+		}
+	}
+
+	for _, bot := range bots {
+		bot.Stop()
+	}
+	wg.Wait()
+	report(0)
+	return collector.errors()
+}
@@ -0,0 +1,198 @@
+package launcher
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/topfreegames/pitaya-bot/models"
+)
+
+// concurrencyTracker records how many bots run at the same time
+type concurrencyTracker struct {
+	mutex   sync.Mutex
+	running int
+	max     int
+	started int
+}
+
+func (c *concurrencyTracker) run(d time.Duration, err error) botFunc {
+	return func(id int) error {
+		c.mutex.Lock()
+		c.running++
+		c.started++
+		if c.running > c.max {
+			c.max = c.running
+		}
+		c.mutex.Unlock()
+
+		time.Sleep(d)
+
+		c.mutex.Lock()
+		c.running--
+		c.mutex.Unlock()
+		return err
+	}
+}
+
+func TestRunConstantArrivalRate(t *testing.T) {
+	tracker := &concurrencyTracker{}
+	dropped := 0
+	executor := &models.Executor{Type: models.ExecutorConstantArrivalRate, Rate: 100, MaxConcurrency: 5}
+
+	start := time.Now()
+	errs := runConstantArrivalRate(executor, 300*time.Millisecond, false, nil, tracker.run(100*time.Millisecond, nil), func() { dropped++ })
+	assert.Empty(t, errs)
+	assert.True(t, time.Since(start) >= 300*time.Millisecond)
+
+	// bots are started at the rate, the ones beyond max concurrency are dropped
+	assert.Equal(t, 5, tracker.max)
+	assert.InDelta(t, 30, tracker.started+dropped, 5)
+	assert.True(t, dropped > 0)
+	assert.Equal(t, 0, tracker.running)
+}
+
+func TestRunConstantArrivalRateStopOnError(t *testing.T) {
+	tracker := &concurrencyTracker{}
+	executor := &models.Executor{Type: models.ExecutorConstantArrivalRate, Rate: 100, MaxConcurrency: 1}
+
+	start := time.Now()
+	errs := runConstantArrivalRate(executor, time.Minute, true, nil, tracker.run(0, errors.New("failed")), func() {})
+	assert.True(t, time.Since(start) < time.Second)
+	assert.NotEmpty(t, errs)
+	assert.Equal(t, errors.New("failed"), errs[0])
+}
+
+func TestRunConstantBots(t *testing.T) {
+	var constantBotsTable = map[string]struct {
+		iterations  int
+		stopOnError bool
+		err         error
+		minStarted  int
+		maxStarted  int
+	}{
+		"duration":      {0, false, nil, 20, 60},
+		"iterations":    {2, false, nil, 10, 10},
+		"stop_on_error": {0, true, errors.New("failed"), 5, 10},
+	}
+
+	for name, table := range constantBotsTable {
+		t.Run(name, func(t *testing.T) {
+			tracker := &concurrencyTracker{}
+			executor := &models.Executor{Type: models.ExecutorConstantBots, Iterations: table.iterations}
+			errs := runConstantBots(5, executor, 100*time.Millisecond, table.stopOnError, nil, tracker.run(10*time.Millisecond, table.err))
+
+			assert.Equal(t, 5, tracker.max)
+			assert.Equal(t, 0, tracker.running)
+			assert.True(t, tracker.started >= table.minStarted && tracker.started <= table.maxStarted, "started %d bots", tracker.started)
+			if table.err == nil {
+				assert.Empty(t, errs)
+			} else {
+				assert.Contains(t, errs, table.err)
+			}
+		})
+	}
+}
+
+func TestRunConstantBotsBackoff(t *testing.T) {
+	tracker := &concurrencyTracker{}
+	executor := &models.Executor{Type: models.ExecutorConstantBots}
+
+	start := time.Now()
+	errs := runConstantBots(1, executor, 250*time.Millisecond, false, nil, tracker.run(0, errors.New("failed")))
+
+	// failures at 0 and 100ms, then the deadline cuts the 200ms backoff short
+	assert.Equal(t, 2, tracker.started)
+	assert.Len(t, errs, 2)
+	assert.True(t, time.Since(start) < 300*time.Millisecond)
+}
+
+func TestStageTarget(t *testing.T) {
+	stages := []*models.Stage{
+		{Duration: "10s", Target: 100},
+		{Duration: "20s", Target: 100},
+		{Duration: "0s", Target: 50},
+		{Duration: "5s", Target: 0},
+	}
+
+	var stageTargetTable = map[string]struct {
+		elapsed time.Duration
+		target  int
+		ok      bool
+	}{
+		"start":        {0, 0, true},
+		"ramp_up":      {5 * time.Second, 50, true},
+		"hold":         {15 * time.Second, 100, true},
+		"instant_jump": {30 * time.Second, 50, true},
+		"ramp_down":    {34 * time.Second, 10, true},
+		"over":         {35 * time.Second, 0, false},
+	}
+
+	for name, table := range stageTargetTable {
+		t.Run(name, func(t *testing.T) {
+			target, ok := stageTarget(stages, table.elapsed)
+			assert.Equal(t, table.target, target)
+			assert.Equal(t, table.ok, ok)
+		})
+	}
+}
+
+func TestRunRampingBots(t *testing.T) {
+	tracker := &concurrencyTracker{}
+	executor := &models.Executor{Type: models.ExecutorRampingBots, Stages: []*models.Stage{
+		{Duration: "100ms", Target: 10},
+		{Duration: "100ms", Target: 10},
+		{Duration: "100ms", Target: 0},
+	}}
+
+	var mutex sync.Mutex
+	maxTarget := 0
+	errs := runRampingBots(executor, 5*time.Millisecond, false, nil, tracker.run(10*time.Millisecond, nil), func(target int) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		if target > maxTarget {
+			maxTarget = target
+		}
+	})
+
+	assert.Empty(t, errs)
+	assert.Equal(t, 10, maxTarget)
+	assert.Equal(t, 10, tracker.max)
+	assert.Equal(t, 0, tracker.running)
+	// bots loop, so there are more iterations than bots
+	assert.True(t, tracker.started > 10)
+}
+
+func TestExecutorsStop(t *testing.T) {
+	var stopTable = map[string]func(stop <-chan struct{}, run botFunc) []error{
+		"constant_arrival_rate": func(stop <-chan struct{}, run botFunc) []error {
+			executor := &models.Executor{Type: models.ExecutorConstantArrivalRate, Rate: 100, MaxConcurrency: 5}
+			return runConstantArrivalRate(executor, time.Minute, false, stop, run, func() {})
+		},
+		"constant_bots": func(stop <-chan struct{}, run botFunc) []error {
+			executor := &models.Executor{Type: models.ExecutorConstantBots}
+			return runConstantBots(5, executor, time.Minute, false, stop, run)
+		},
+		"ramping_bots": func(stop <-chan struct{}, run botFunc) []error {
+			executor := &models.Executor{Type: models.ExecutorRampingBots, Stages: []*models.Stage{{Duration: "1m", Target: 5}}}
+			return runRampingBots(executor, 5*time.Millisecond, false, stop, run, func(target int) {})
+		},
+	}
+
+	for name, runExecutor := range stopTable {
+		t.Run(name, func(t *testing.T) {
+			tracker := &concurrencyTracker{}
+			stop := make(chan struct{})
+			time.AfterFunc(50*time.Millisecond, func() { close(stop) })
+
+			// running bots finish their iteration, no new one is started
+			start := time.Now()
+			errs := runExecutor(stop, tracker.run(10*time.Millisecond, nil))
+			assert.Empty(t, errs)
+			assert.True(t, time.Since(start) < time.Second)
+			assert.Equal(t, 0, tracker.running)
+		})
+	}
+}
@@ -0,0 +1,194 @@
+package launcher
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/topfreegames/pitaya-bot/models"
+)
+
+// argPattern matches the "$args.<name>" references of a fragment
+var argPattern = regexp.MustCompile(`\$args\.(\w+)`)
+
+// fragment is a reusable list of operations that specs can include
+type fragment struct {
+	Operations []*models.Operation `json:"operations"`
+}
+
+// isFragment returns true if the decoded file is a fragment instead of a spec
+func isFragment(content interface{}) bool {
+	fields, ok := content.(map[string]interface{})
+	if !ok {
+		return false
+	}
+
+	_, hasOperations := fields["operations"]
+	_, hasSequentialOperations := fields["sequentialOperations"]
+	return hasOperations && !hasSequentialOperations
+}
+
+// resolveIncludes replaces every include operation, also inside nested
+// operations, by the operations of the fragment it references. Relative
+// fragment paths are resolved from dir and stack holds the files being
+// resolved, to detect cycles
+func resolveIncludes(ops []*models.Operation, dir string, stack []string) ([]*models.Operation, error) {
+	if ops == nil {
+		return nil, nil
+	}
+
+	ret := make([]*models.Operation, 0, len(ops))
+	for _, op := range ops {
+		if op == nil {
+			ret = append(ret, op)
+			continue
+		}
+
+		if op.Type == "include" {
+			included, err := readFragment(op, dir, stack)
+			if err != nil {
+				return nil, err
+			}
+			ret = append(ret, included...)
+			continue
+		}
+
+		var err error
+		if op.Operations, err = resolveIncludes(op.Operations, dir, stack); err != nil {
+			return nil, err
+		}
+		if op.Else, err = resolveIncludes(op.Else, dir, stack); err != nil {
+			return nil, err
+		}
+		for _, choice := range op.Choices {
+			if choice == nil {
+				continue
+			}
+			if choice.Operations, err = resolveIncludes(choice.Operations, dir, stack); err != nil {
+				return nil, err
+			}
+		}
+		ret = append(ret, op)
+	}
+
+	return ret, nil
+}
+
+func readFragment(op *models.Operation, dir string, stack []string) ([]*models.Operation, error) {
+	path := op.URI
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, p := range stack {
+		if p == path {
+			return nil, fmt.Errorf("include cycle: %s -> %s", strings.Join(stack, " -> "), path)
+		}
+	}
+
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var content interface{}
+	if err := unmarshalFile(path, raw, &content); err != nil {
+		return nil, fmt.Errorf("failed to read fragment %s", err)
+	}
+
+	content, err = substituteArgs(content, op.Args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to include fragment %s: %s", path, err)
+	}
+
+	substituted, err := json.Marshal(content)
+	if err != nil {
+		return nil, err
+	}
+
+	var frag fragment
+	if err := json.Unmarshal(substituted, &frag); err != nil {
+		return nil, fmt.Errorf("failed to read fragment %s: %s", path, err)
+	}
+	if frag.Operations == nil {
+		return nil, fmt.Errorf("fragment %s has no operations", path)
+	}
+
+	fragmentStack := make([]string, len(stack), len(stack)+1)
+	copy(fragmentStack, stack)
+	return resolveIncludes(frag.Operations, filepath.Dir(path), append(fragmentStack, path))
+}
+
+// substituteArgs replaces the "$args.<name>" references inside value by the
+// include args. A string that is a single reference takes the arg value as
+// is, while references embedded in a longer string are replaced by the arg
+// text, so "room-$args.id" becomes "room-42"
+func substituteArgs(value interface{}, args map[string]interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case string:
+		if match := argPattern.FindStringSubmatchIndex(v); match != nil && match[0] == 0 && match[1] == len(v) {
+			return lookupArg(args, v[match[2]:match[3]])
+		}
+		return interpolateArgs(v, args)
+	case map[string]interface{}:
+		for key, elem := range v {
+			substituted, err := substituteArgs(elem, args)
+			if err != nil {
+				return nil, err
+			}
+			v[key] = substituted
+		}
+		return v, nil
+	case []interface{}:
+		for i, elem := range v {
+			substituted, err := substituteArgs(elem, args)
+			if err != nil {
+				return nil, err
+			}
+			v[i] = substituted
+		}
+		return v, nil
+	default:
+		return v, nil
+	}
+}
+
+func lookupArg(args map[string]interface{}, name string) (interface{}, error) {
+	arg, ok := args[name]
+	if !ok {
+		return nil, fmt.Errorf("missing arg %s", name)
+	}
+	return arg, nil
+}
+
+// interpolateArgs replaces the references embedded in s by the text of the
+// args, which must be scalars
+func interpolateArgs(s string, args map[string]interface{}) (string, error) {
+	var err error
+	ret := argPattern.ReplaceAllStringFunc(s, func(ref string) string {
+		name := argPattern.FindStringSubmatch(ref)[1]
+		arg, lookupErr := lookupArg(args, name)
+		switch arg.(type) {
+		case map[string]interface{}, []interface{}:
+			lookupErr = fmt.Errorf("arg %s can't be embedded in a string", name)
+		}
+		if lookupErr != nil {
+			if err == nil {
+				err = lookupErr
+			}
+			return ref
+		}
+		return fmt.Sprint(arg)
+	})
+	if err != nil {
+		return "", err
+	}
+	return ret, nil
+}
@@ -0,0 +1,100 @@
+package launcher
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/topfreegames/pitaya-bot/models"
+)
+
+func writeFiles(t *testing.T, files map[string]string) string {
+	dir, err := ioutil.TempDir("", "pitaya-bot-specs")
+	assert.NoError(t, err)
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		assert.NoError(t, ioutil.WriteFile(path, []byte(content), 0644))
+	}
+	return dir
+}
+
+func TestGetSpecsIncludes(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"spec.json": `{
+			"numberOfInstances": 1,
+			"sequentialOperations": [
+				{"type": "include", "uri": "fragments/login.json", "args": {"route": "connector.playerHandler.create", "handler": "roomHandler", "id": 42}},
+				{"type": "loop", "count": 2, "operations": [
+					{"type": "include", "uri": "fragments/reconnect.json"}
+				]}
+			]
+		}`,
+		"fragments/login.json": `{
+			"operations": [
+				{"type": "request", "uri": "$args.route"},
+				{"type": "notify", "uri": "connector.$args.handler.join", "args": {"room": "room-$args.id"}},
+				{"type": "include", "uri": "reconnect.json"}
+			]
+		}`,
+		"fragments/reconnect.json": `{"operations": [{"type": "function", "uri": "reconnect"}]}`,
+	})
+	defer os.RemoveAll(dir)
+
+	specs, err := GetSpecs(dir)
+	assert.NoError(t, err)
+	assert.Len(t, specs, 1)
+
+	ops := specs[0].SequentialOperations
+	assert.Len(t, ops, 4)
+	assert.Equal(t, &models.Operation{Type: "request", URI: "connector.playerHandler.create"}, ops[0])
+	assert.Equal(t, &models.Operation{Type: "notify", URI: "connector.roomHandler.join", Args: map[string]interface{}{"room": "room-42"}}, ops[1])
+	assert.Equal(t, &models.Operation{Type: "function", URI: "reconnect"}, ops[2])
+	assert.Equal(t, "loop", ops[3].Type)
+	assert.Equal(t, []*models.Operation{{Type: "function", URI: "reconnect"}}, ops[3].Operations)
+}
+
+func TestGetSpecsIncludesErrors(t *testing.T) {
+	var includeErrorsTable = map[string]struct {
+		files map[string]string
+		err   string
+	}{
+		"cycle": {map[string]string{
+			"spec.json": `{"sequentialOperations": [{"type": "include", "uri": "a.frag"}]}`,
+			"a.frag":    `{"operations": [{"type": "include", "uri": "b.frag"}]}`,
+			"b.frag":    `{"operations": [{"type": "include", "uri": "a.frag"}]}`,
+		}, "include cycle: "},
+		"missing_arg": {map[string]string{
+			"spec.json": `{"sequentialOperations": [{"type": "include", "uri": "a.frag"}]}`,
+			"a.frag":    `{"operations": [{"type": "request", "uri": "$args.route"}]}`,
+		}, "missing arg route"},
+		"missing_embedded_arg": {map[string]string{
+			"spec.json": `{"sequentialOperations": [{"type": "include", "uri": "a.frag", "args": {"a": 1}}]}`,
+			"a.frag":    `{"operations": [{"type": "request", "uri": "connector.$args.a.$args.handler"}]}`,
+		}, "missing arg handler"},
+		"embedded_object_arg": {map[string]string{
+			"spec.json": `{"sequentialOperations": [{"type": "include", "uri": "a.frag", "args": {"room": {"id": 1}}}]}`,
+			"a.frag":    `{"operations": [{"type": "request", "uri": "connector.$args.room"}]}`,
+		}, "arg room can't be embedded in a string"},
+		"no_operations": {map[string]string{
+			"spec.json": `{"sequentialOperations": [{"type": "include", "uri": "a.frag"}]}`,
+			"a.frag":    `{}`,
+		}, "has no operations"},
+		"missing_file": {map[string]string{
+			"spec.json": `{"sequentialOperations": [{"type": "include", "uri": "a.frag"}]}`,
+		}, "no such file or directory"},
+	}
+
+	for name, table := range includeErrorsTable {
+		t.Run(name, func(t *testing.T) {
+			dir := writeFiles(t, table.files)
+			defer os.RemoveAll(dir)
+
+			_, err := GetSpecs(dir)
+			assert.Error(t, err)
+			assert.Contains(t, err.Error(), table.err)
+		})
+	}
+}
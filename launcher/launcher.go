@@ -1,20 +1,25 @@
 package launcher
 
 import (
-	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"math/rand"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"runtime"
 	"strings"
 	"sync"
+	"syscall"
 	"time"
 
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
+	"github.com/topfreegames/pitaya-bot/constants"
+	"github.com/topfreegames/pitaya-bot/metrics"
 	"github.com/topfreegames/pitaya-bot/models"
+	"github.com/topfreegames/pitaya-bot/report"
+	"github.com/topfreegames/pitaya-bot/results"
 	"github.com/topfreegames/pitaya-bot/runner"
 	"github.com/topfreegames/pitaya-bot/state"
 	"k8s.io/client-go/kubernetes"
@@ -22,14 +27,33 @@ import (
 	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
 )
 
+// readSpec reads the spec file and resolves its includes. It returns a nil
+// spec if the file is a fragment
 func readSpec(specPath string) (*models.Spec, error) {
 	raw, err := ioutil.ReadFile(specPath)
 	if err != nil {
 		return nil, err
 	}
 
+	var content interface{}
+	if err = unmarshalFile(specPath, raw, &content); err != nil {
+		return nil, err
+	}
+	if isFragment(content) {
+		return nil, nil
+	}
+
 	var spec models.Spec
-	err = json.Unmarshal(raw, &spec)
+	if err = unmarshalFile(specPath, raw, &spec); err != nil {
+		return nil, err
+	}
+
+	absPath, err := filepath.Abs(specPath)
+	if err != nil {
+		return nil, err
+	}
+
+	spec.SequentialOperations, err = resolveIncludes(spec.SequentialOperations, filepath.Dir(absPath), []string{absPath})
 	return &spec, err
 }
 
@@ -43,6 +67,9 @@ func validFile(info os.FileInfo) bool {
 	if strings.Contains(info.Name(), ".json") {
 		return true
 	}
+	if isYAML(info.Name()) {
+		return true
+	}
 	return false
 }
 
@@ -60,7 +87,7 @@ func newKubernetesClientset(config *viper.Viper, logger logrus.FieldLogger) *kub
 	return clientset
 }
 
-// GetSpecs will walk through specsDirectory and transform all spec JSONs into Spec objects
+// GetSpecs will walk through specsDirectory and transform all spec JSONs and YAMLs into Spec objects
 func GetSpecs(specsDirectory string) ([]*models.Spec, error) {
 	ret := make([]*models.Spec, 0)
 	err := filepath.Walk(specsDirectory,
@@ -80,6 +107,10 @@ func GetSpecs(specsDirectory string) ([]*models.Spec, error) {
 			if err != nil {
 				return err
 			}
+			if spec == nil {
+				// fragments are only run when included by specs
+				return nil
+			}
 
 			spec.Name = path
 			ret = append(ret, spec)
@@ -104,14 +135,19 @@ func runClients(app *state.App, spec *models.Spec, config *viper.Viper, logger l
 	for i := 0; i < spec.NumberOfInstances; i++ {
 		wg.Add(1)
 		go func(i int) {
+			defer wg.Done()
 			sleepDuration := time.Duration(random.Intn(int(config.GetDuration("bot.operation.maxSleep"))))
-			time.Sleep(sleepDuration)
+			select {
+			case <-time.After(sleepDuration):
+			case <-app.Stopped:
+				// bots that did not start yet are not started anymore
+				return
+			}
 			if err := runner.Run(app, config, spec, i, logger); err != nil {
 				errmutex.Lock()
 				compoundError = append(compoundError, err)
 				errmutex.Unlock()
 			}
-			wg.Done()
 		}(i)
 	}
 
@@ -124,8 +160,35 @@ func runSpec(app *state.App, spec *models.Spec, config *viper.Viper, duration fl
 		"spec": spec.Name,
 	})
 
+	executor := spec.GetExecutor()
+	stopOnError := config.GetBool("bot.operation.stopOnError")
+	testDuration := time.Duration(duration * float64(time.Second))
+	run := func(id int) error {
+		return runner.Run(app, config, spec, id, logger)
+	}
+
+	switch executor.Type {
+	case models.ExecutorConstantArrivalRate:
+		logger.Debugf("Starting %.2f bots per second\n", executor.Rate)
+		return runConstantArrivalRate(executor, testDuration, stopOnError, app.Stopped, run, func() {
+			metrics.ReportCount(app.MetricsReporter, constants.DroppedIterationCount, map[string]string{"spec": spec.Name}, 1, logger)
+		})
+	case models.ExecutorConstantBots:
+		logger.Debugf("Launching %d looping bots\n", spec.NumberOfInstances)
+		return runConstantBots(spec.NumberOfInstances, executor, testDuration, stopOnError, app.Stopped, run)
+	case models.ExecutorRampingBots:
+		logger.Debugf("Ramping bots through %d stages\n", len(executor.Stages))
+		tags := map[string]string{"spec": spec.Name}
+		return runRampingBots(executor, time.Second, stopOnError, app.Stopped, run, func(target int) {
+			metrics.ReportGauge(app.MetricsReporter, constants.TargetBots, tags, float64(target), logger)
+		})
+	}
+
 	logger.Debugf("Launching %d bots\n", spec.NumberOfInstances)
+	return runBatches(app, spec, config, duration, logger)
+}
 
+func runBatches(app *state.App, spec *models.Spec, config *viper.Viper, duration float64, logger logrus.FieldLogger) []error {
 	var compoundError []error
 	start := time.Now().UTC()
 	for {
@@ -137,7 +200,7 @@ func runSpec(app *state.App, spec *models.Spec, config *viper.Viper, duration fl
 		}
 
 		elapsed := time.Now().UTC().Sub(start)
-		if elapsed.Seconds() > duration {
+		if elapsed.Seconds() > duration || app.IsStopped() {
 			break
 		}
 	}
@@ -145,6 +208,148 @@ func runSpec(app *state.App, spec *models.Spec, config *viper.Viper, duration fl
 	return compoundError
 }
 
+// writeSummary prints the summary table and, if summaryOut is set, writes it
+// as JSON to that file
+func writeSummary(summary *metrics.Summary, summaryOut string, logger logrus.FieldLogger) {
+	if err := summary.WriteTable(os.Stdout); err != nil {
+		logger.WithError(err).Error("Failed to print summary")
+	}
+	if summaryOut == "" {
+		return
+	}
+
+	f, err := os.Create(summaryOut)
+	if err != nil {
+		logger.WithError(err).Error("Failed to create summary file")
+		return
+	}
+	defer f.Close()
+	if err := summary.WriteJSON(f); err != nil {
+		logger.WithError(err).Error("Failed to write summary file")
+	}
+}
+
+// GetThresholds returns the thresholds of the config, failing if any of them
+// is malformed
+func GetThresholds(config *viper.Viper) ([]*metrics.Threshold, error) {
+	var thresholds []*metrics.Threshold
+	if err := config.UnmarshalKey("bot.thresholds", &thresholds); err != nil {
+		return nil, err
+	}
+	for _, t := range thresholds {
+		if err := t.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	return thresholds, nil
+}
+
+// watchThresholds aborts the run when a threshold marked to abort on fail is
+// crossed, checking the summary every interval until finished is closed.
+// aborted is closed if the run was aborted
+func watchThresholds(
+	app *state.App,
+	thresholds []*metrics.Threshold,
+	start time.Time,
+	interval time.Duration,
+	finished <-chan struct{},
+	aborted chan<- struct{},
+	logger logrus.FieldLogger,
+) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-finished:
+			return
+		case <-ticker.C:
+		}
+
+		elapsed := time.Since(start)
+		if result, ok := metrics.ShouldAbort(thresholds, app.Summary.Summary(elapsed), elapsed); ok {
+			logger.Errorf("Threshold %s crossed with %g, aborting run", result.Threshold, result.Value)
+			close(aborted)
+			app.Abort(state.AbortReasonThreshold)
+			return
+		}
+	}
+}
+
+// checkThresholds logs the outcome of each threshold and returns them, along
+// with true if all of them passed
+func checkThresholds(thresholds []*metrics.Threshold, summary *metrics.Summary, logger logrus.FieldLogger) ([]*metrics.ThresholdResult, bool) {
+	passed := true
+	thresholdResults := make([]*metrics.ThresholdResult, len(thresholds))
+	for idx, t := range thresholds {
+		result := t.Check(summary)
+		thresholdResults[idx] = result
+		switch {
+		case result.NoData:
+			logger.Errorf("Threshold %s failed: route was never reported", t)
+		case !result.Passed:
+			logger.Errorf("Threshold %s failed with %g", t, result.Value)
+		default:
+			logger.Infof("Threshold %s passed with %g", t, result.Value)
+		}
+		passed = passed && result.Passed
+	}
+	return thresholdResults, passed
+}
+
+// GetReportOutputs returns the report files of the config, failing if any of
+// them is malformed
+func GetReportOutputs(config *viper.Viper) ([]*report.Output, error) {
+	var outputs []*report.Output
+	if err := config.UnmarshalKey("bot.reports", &outputs); err != nil {
+		return nil, err
+	}
+	for _, output := range outputs {
+		if err := output.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	return outputs, nil
+}
+
+// newResultsSink returns the sink writing the samples of every bot to the
+// results file of the config, or nil if there is none
+func newResultsSink(config *viper.Viper) (*results.FileSink, error) {
+	path := config.GetString("bot.results.path")
+	if path == "" {
+		return nil, nil
+	}
+	return results.NewFileSink(path, config.GetString("bot.results.format"), config.GetInt("bot.results.bufferSize"))
+}
+
+// writeReports writes the run report to each of the outputs
+func writeReports(runReport *report.Report, outputs []*report.Output, logger logrus.FieldLogger) {
+	for _, output := range outputs {
+		if err := runReport.WriteFile(output); err != nil {
+			logger.WithError(err).Errorf("Failed to write %s report", output.Format)
+			continue
+		}
+		logger.Infof("Wrote %s report to %s", output.Format, output.Path)
+	}
+}
+
+// handleSignals interrupts the run on the first signal, so no new bots are
+// started, and aborts the running bots once gracePeriod is over or when a
+// second signal arrives
+func handleSignals(app *state.App, signals <-chan os.Signal, gracePeriod time.Duration, logger logrus.FieldLogger) {
+	sig := <-signals
+	logger.Warnf("Received %s, waiting %s for running bots to finish", sig, gracePeriod)
+	app.Interrupt()
+
+	select {
+	case sig = <-signals:
+		logger.Warnf("Received %s again, aborting running bots", sig)
+	case <-time.After(gracePeriod):
+		logger.Warn("Grace period is over, aborting running bots")
+	}
+	app.Abort(state.AbortReasonSignal)
+}
+
 // Launch launches the bot spec
 func Launch(
 	app *state.App,
@@ -152,6 +357,7 @@ func Launch(
 	specsDirectory string,
 	duration float64,
 	shouldReportMetrics bool,
+	summaryOut string,
 	logger logrus.FieldLogger,
 ) {
 	logger = logger.WithFields(logrus.Fields{
@@ -162,15 +368,59 @@ func Launch(
 	if err != nil {
 		logger.Fatal(err)
 	}
+	for _, spec := range specs {
+		if err := spec.GetExecutor().Validate(); err != nil {
+			logger.WithField("spec", spec.Name).Fatal(err)
+		}
+		for idx, step := range spec.SequentialOperations {
+			if err := step.Validate(); err != nil {
+				logger.WithField("spec", spec.Name).Fatalf("invalid step=[%d]: %s", idx, err)
+			}
+		}
+	}
+	thresholds, err := GetThresholds(config)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	outputs, err := GetReportOutputs(config)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	sink, err := newResultsSink(config)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	if sink != nil {
+		app.Results = sink
+	}
 	logger.Infof("Found %d specs to be executed", len(specs))
 
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(signals)
+	go handleSignals(app, signals, config.GetDuration("bot.shutdown.gracePeriod"), logger)
+
+	start := time.Now()
+	runReport := report.NewReport(start)
+	finished := make(chan struct{})
+	aborted := make(chan struct{})
+	go watchThresholds(app, thresholds, start, time.Second, finished, aborted, logger)
+	if interval := config.GetDuration("prometheus.pushgateway.interval"); app.Pusher != nil && interval > 0 {
+		go app.Pusher.PushEvery(interval, finished, func(err error) {
+			logger.WithError(err).Warn("Failed to push metrics")
+		})
+	}
+
 	var wg sync.WaitGroup
 	errmutex := sync.Mutex{}
 	compoundErrorHist := make(map[string]int)
 	for _, spec := range specs {
 		wg.Add(1)
 		go func(spec *models.Spec) {
-			if errs := runSpec(app, spec, config, duration, logger); errs != nil {
+			specStart := time.Now()
+			errs := runSpec(app, spec, config, duration, logger)
+			runReport.AddSpec(spec, time.Since(specStart), errs)
+			if errs != nil {
 				errmutex.Lock()
 				for _, err := range errs {
 					compoundErrorHist[err.Error()]++
@@ -182,11 +432,48 @@ func Launch(
 	}
 
 	wg.Wait()
+	close(finished)
+	if sink != nil {
+		if err := sink.Close(); err != nil {
+			logger.WithError(err).Error("Failed to write results file")
+		}
+	}
+	if app.Pusher != nil {
+		// the final values are pushed, so there is no scrape to wait for
+		if err := app.Pusher.Push(); err != nil {
+			logger.WithError(err).Error("Failed to push metrics")
+		}
+	}
 
 	logger.Info("Finished running bots")
 	app.FinishedExecution = true
 
-	if shouldReportMetrics {
+	summary := app.Summary.Summary(time.Since(start))
+	writeSummary(summary, summaryOut, logger)
+	thresholdResults, passed := checkThresholds(thresholds, summary, logger)
+	thresholdsFailed := !passed
+	select {
+	case <-aborted:
+		thresholdsFailed = true
+	default:
+	}
+
+	runReport.Finish(summary, thresholdResults, app.IsInterrupted())
+	writeReports(runReport, outputs, logger)
+
+	if app.IsInterrupted() {
+		// the metrics collector may already be gone, so the partial results
+		// are reported without waiting for it
+		logger.WithFields(logrus.Fields{
+			"errors": compoundErrorHist,
+		}).Warn("Spec execution interrupted")
+		if thresholdsFailed {
+			os.Exit(constants.ExitCodeThresholdsFailed)
+		}
+		os.Exit(constants.ExitCodeInterrupted)
+	}
+
+	if shouldReportMetrics && app.WaitScrape {
 		logger.Info("Waiting for metrics to be collected...")
 		select {
 		case <-app.DieChan: // when dieChan is closed the application can quit
@@ -199,6 +486,11 @@ func Launch(
 		logger.WithFields(logrus.Fields{
 			"errors": compoundErrorHist,
 		}).Error("Spec execution failed")
-		os.Exit(1)
+		if !thresholdsFailed {
+			os.Exit(1)
+		}
+	}
+	if thresholdsFailed {
+		os.Exit(constants.ExitCodeThresholdsFailed)
 	}
 }
@@ -0,0 +1,113 @@
+package launcher
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/topfreegames/pitaya-bot/constants"
+	"github.com/topfreegames/pitaya-bot/metrics"
+	"github.com/topfreegames/pitaya-bot/report"
+	"github.com/topfreegames/pitaya-bot/state"
+)
+
+func TestGetThresholds(t *testing.T) {
+	var getThresholdsTable = map[string]struct {
+		yaml  string
+		count int
+		err   error
+	}{
+		"none": {"bot:\n  push:\n    queueSize: 10\n", 0, nil},
+		"valid": {`
+bot:
+  thresholds:
+    - route: connector.playerHandler.authenticate
+      metric: p95
+      max: 200
+    - route: connector.playerHandler.authenticate
+      metric: errorRate
+      max: 0.01
+      abortOnFail: true
+      abortAfter: 30s
+`, 2, nil},
+		"invalid": {`
+bot:
+  thresholds:
+    - route: connector.playerHandler.authenticate
+      metric: p95
+`, 0, errors.New("threshold on connector.playerHandler.authenticate p95 has neither min nor max")},
+	}
+
+	for name, table := range getThresholdsTable {
+		t.Run(name, func(t *testing.T) {
+			config := viper.New()
+			config.SetConfigType("yaml")
+			assert.NoError(t, config.ReadConfig(bytes.NewBufferString(table.yaml)))
+
+			thresholds, err := GetThresholds(config)
+			assert.Equal(t, table.err, err)
+			assert.Len(t, thresholds, table.count)
+		})
+	}
+
+	config := viper.New()
+	config.SetConfigType("yaml")
+	assert.NoError(t, config.ReadConfig(bytes.NewBufferString(getThresholdsTable["valid"].yaml)))
+	thresholds, _ := GetThresholds(config)
+	assert.Equal(t, 0.01, *thresholds[1].Max)
+	assert.True(t, thresholds[1].AbortOnFail)
+	assert.Equal(t, 30*time.Second, thresholds[1].GetAbortAfter())
+}
+
+func TestWatchThresholds(t *testing.T) {
+	max := 100.0
+	thresholds := []*metrics.Threshold{{Route: "connector.login", Metric: "max", Max: &max, AbortOnFail: true}}
+	app := state.NewApp(viper.New(), false)
+	finished := make(chan struct{})
+	aborted := make(chan struct{})
+	go watchThresholds(app, thresholds, time.Now(), time.Millisecond, finished, aborted, logrus.New())
+
+	app.Summary.ReportSummary(constants.ResponseTime, map[string]string{"route": "connector.login"}, 50)
+	time.Sleep(10 * time.Millisecond)
+	assert.False(t, app.IsInterrupted())
+
+	app.Summary.ReportSummary(constants.ResponseTime, map[string]string{"route": "connector.login"}, 150)
+	select {
+	case <-aborted:
+	case <-time.After(time.Second):
+		t.Fatal("run was not aborted")
+	}
+	<-app.Aborted
+	assert.False(t, app.IsInterrupted())
+	assert.True(t, app.IsStopped())
+	assert.Equal(t, state.AbortReasonThreshold, app.GetAbortReason())
+	close(finished)
+}
+
+func TestGetReportOutputs(t *testing.T) {
+	config := viper.New()
+	config.SetConfigType("yaml")
+	assert.NoError(t, config.ReadConfig(bytes.NewBufferString(`
+bot:
+  reports:
+    - format: junit
+      path: ./reports/junit.xml
+    - format: html
+      path: ./reports/report.html
+`)))
+
+	outputs, err := GetReportOutputs(config)
+	assert.NoError(t, err)
+	assert.Equal(t, []*report.Output{
+		{Format: "junit", Path: "./reports/junit.xml"},
+		{Format: "html", Path: "./reports/report.html"},
+	}, outputs)
+
+	config.Set("bot.reports", []map[string]interface{}{{"format": "pdf", "path": "report.pdf"}})
+	_, err = GetReportOutputs(config)
+	assert.Equal(t, errors.New("unknown report format pdf"), err)
+}
@@ -0,0 +1,87 @@
+package metrics
+
+import (
+	"math"
+	"sort"
+)
+
+// histogramGrowth is the ratio between the bounds of consecutive buckets, so
+// percentiles are within 1% of the latencies reported
+const histogramGrowth = 1.02
+
+// histogramMinValue is the lowest latency told apart in milliseconds, lower
+// latencies fall in the first bucket
+const histogramMinValue = 0.001
+
+// latencyHistogram keeps latencies in buckets with exponentially growing
+// bounds, so its size depends on the range of the latencies and not on how
+// many were reported. Bucket i holds the latencies in
+// (histogramMinValue*histogramGrowth^(i-1), histogramMinValue*histogramGrowth^i]
+type latencyHistogram struct {
+	buckets map[int]int
+	count   int
+	sum     float64
+	min     float64
+	max     float64
+}
+
+func newLatencyHistogram() *latencyHistogram {
+	return &latencyHistogram{buckets: make(map[int]int)}
+}
+
+func (h *latencyHistogram) add(value float64) {
+	if h.count == 0 || value < h.min {
+		h.min = value
+	}
+	if h.count == 0 || value > h.max {
+		h.max = value
+	}
+	h.count++
+	h.sum += value
+	h.buckets[bucketIndex(value)]++
+}
+
+// percentile returns the nearest rank percentile q, estimated by the middle
+// of its bucket
+func (h *latencyHistogram) percentile(q float64) float64 {
+	if h.count == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(q * float64(h.count)))
+	if rank >= h.count {
+		return h.max
+	}
+	if rank < 1 {
+		rank = 1
+	}
+
+	indexes := make([]int, 0, len(h.buckets))
+	for idx := range h.buckets {
+		indexes = append(indexes, idx)
+	}
+	sort.Ints(indexes)
+
+	seen := 0
+	for _, idx := range indexes {
+		seen += h.buckets[idx]
+		if seen >= rank {
+			return math.Min(math.Max(bucketValue(idx), h.min), h.max)
+		}
+	}
+	return h.max
+}
+
+func bucketIndex(value float64) int {
+	if value <= histogramMinValue {
+		return 0
+	}
+	return int(math.Ceil(math.Log(value/histogramMinValue) / math.Log(histogramGrowth)))
+}
+
+func bucketValue(idx int) float64 {
+	if idx == 0 {
+		return 0
+	}
+	lower := histogramMinValue * math.Pow(histogramGrowth, float64(idx-1))
+	return (lower + lower*histogramGrowth) / 2
+}
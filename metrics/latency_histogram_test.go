@@ -0,0 +1,48 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLatencyHistogramPercentile(t *testing.T) {
+	h := newLatencyHistogram()
+	assert.Equal(t, 0.0, h.percentile(0.5))
+
+	for i := 1; i <= 10000; i++ {
+		h.add(float64(i) / 10)
+	}
+	assert.Equal(t, 10000, h.count)
+	assert.Equal(t, 0.1, h.min)
+	assert.Equal(t, 1000.0, h.max)
+
+	var percentileTable = map[string]struct {
+		q        float64
+		expected float64
+	}{
+		"p0":  {0, 0.1},
+		"p50": {0.5, 500},
+		"p90": {0.9, 900},
+		"p99": {0.99, 990},
+	}
+
+	for name, table := range percentileTable {
+		t.Run(name, func(t *testing.T) {
+			assert.InEpsilon(t, table.expected, h.percentile(table.q), 0.01)
+		})
+	}
+
+	// the buckets grow with the range of the latencies, not their number
+	assert.True(t, len(h.buckets) < 500)
+}
+
+func TestLatencyHistogramSubMillisecond(t *testing.T) {
+	h := newLatencyHistogram()
+	h.add(0)
+	h.add(0.25)
+	h.add(0.5)
+	assert.Equal(t, 0.0, h.percentile(0.3))
+	assert.InEpsilon(t, 0.25, h.percentile(0.5), 0.01)
+	assert.Equal(t, 0.5, h.percentile(1))
+}
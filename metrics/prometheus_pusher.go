@@ -0,0 +1,55 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/push"
+)
+
+// PrometheusPusher sends the prometheus metrics to a Pushgateway, so the run
+// doesn't have to be kept alive until they are scraped
+type PrometheusPusher struct {
+	url      string
+	job      string
+	grouping map[string]string
+	gatherer prometheus.Gatherer
+}
+
+// NewPrometheusPusher returns a pusher sending the metrics of the default
+// registry to the Pushgateway at url. The metrics are grouped by job, the
+// run id and the host, so concurrent runs don't replace each other
+func NewPrometheusPusher(url, job, runID string) *PrometheusPusher {
+	grouping := push.HostnameGroupingKey()
+	grouping["run_id"] = runID
+	return &PrometheusPusher{
+		url:      url,
+		job:      job,
+		grouping: grouping,
+		gatherer: prometheus.DefaultGatherer,
+	}
+}
+
+// Push replaces the metrics of the run in the Pushgateway with their
+// current values
+func (p *PrometheusPusher) Push() error {
+	return push.FromGatherer(p.job, p.grouping, p.url, p.gatherer)
+}
+
+// PushEvery pushes the metrics every interval until stop is closed, calling
+// onError when a push fails
+func (p *PrometheusPusher) PushEvery(interval time.Duration, stop <-chan struct{}, onError func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if err := p.Push(); err != nil {
+				onError(err)
+			}
+		}
+	}
+}
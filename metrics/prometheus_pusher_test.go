@@ -0,0 +1,78 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrometheusPusherPush(t *testing.T) {
+	var pushTable = map[string]struct {
+		status int
+		err    bool
+	}{
+		"accepted": {http.StatusAccepted, false},
+		"failed":   {http.StatusInternalServerError, true},
+	}
+
+	for name, table := range pushTable {
+		t.Run(name, func(t *testing.T) {
+			paths := make(chan string, 1)
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, http.MethodPut, r.Method)
+				paths <- r.URL.Path
+				w.WriteHeader(table.status)
+			}))
+			defer server.Close()
+
+			pusher := NewPrometheusPusher(server.URL, "pitaya-bot", "run-1")
+			err := pusher.Push()
+			if table.err {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			path := <-paths
+			assert.Contains(t, path, "/metrics/job/pitaya-bot/")
+			assert.Contains(t, path, "/run_id/run-1")
+			assert.Contains(t, path, "/instance/")
+		})
+	}
+}
+
+func TestPrometheusPusherPushEvery(t *testing.T) {
+	pushes := make(chan struct{}, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		pushes <- struct{}{}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	pusher := NewPrometheusPusher(server.URL, "pitaya-bot", "run-1")
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		pusher.PushEvery(10*time.Millisecond, stop, func(err error) {
+			assert.NoError(t, err)
+		})
+	}()
+
+	for i := 0; i < 2; i++ {
+		select {
+		case <-pushes:
+		case <-time.After(time.Second):
+			assert.FailNow(t, "metrics weren't pushed")
+		}
+	}
+	close(stop)
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		assert.FailNow(t, "pusher didn't stop")
+	}
+}
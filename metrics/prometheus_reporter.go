@@ -11,9 +11,14 @@ import (
 	"github.com/topfreegames/pitaya/v2/constants"
 )
 
+// DefaultBuckets are the buckets of the histograms in milliseconds, from 1ms
+// to 10s
+var DefaultBuckets = []float64{1, 5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000, 10000}
+
 var (
 	prometheusReporter *PrometheusReporter
 	once               sync.Once
+	serveOnce          sync.Once
 )
 
 // PrometheusReporter reports metrics to prometheus
@@ -25,7 +30,7 @@ type PrometheusReporter struct {
 	gaugeReportersMap     map[string]*prometheus.GaugeVec
 }
 
-func (p *PrometheusReporter) registerMetrics(constLabels map[string]string) {
+func (p *PrometheusReporter) registerMetrics(constLabels map[string]string, buckets []float64) {
 	constLabels["game"] = p.game
 	constLabels["clientType"] = "pitaya-bot"
 
@@ -47,7 +52,8 @@ func (p *PrometheusReporter) registerMetrics(constLabels map[string]string) {
 			Namespace:   fmt.Sprintf("pitaya_bot_%s", p.game),
 			Subsystem:   "handler",
 			Name:        pbConstants.ResponseTimeHistogram,
-			Help:        "histogram of the time to process a msg in nanoseconds",
+			Help:        "histogram of the time to process a msg in milliseconds",
+			Buckets:     buckets,
 			ConstLabels: constLabels,
 		},
 		[]string{"route"},
@@ -64,6 +70,262 @@ func (p *PrometheusReporter) registerMetrics(constLabels map[string]string) {
 		[]string{"route"},
 	)
 
+	p.countReportersMap[pbConstants.ChoiceCount] = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace:   fmt.Sprintf("pitaya_bot_%s", p.game),
+			Subsystem:   "bot",
+			Name:        pbConstants.ChoiceCount,
+			Help:        "the number of times each branch of a choice operation was taken",
+			ConstLabels: constLabels,
+		},
+		[]string{"choice", "branch"},
+	)
+
+	p.countReportersMap[pbConstants.FailureCount] = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace:   fmt.Sprintf("pitaya_bot_%s", p.game),
+			Subsystem:   "bot",
+			Name:        pbConstants.FailureCount,
+			Help:        "the number of operation failures that didn't stop the bot, such as soft assertions",
+			ConstLabels: constLabels,
+		},
+		[]string{"type", "route", "policy"},
+	)
+
+	p.countReportersMap[pbConstants.DroppedIterationCount] = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace:   fmt.Sprintf("pitaya_bot_%s", p.game),
+			Subsystem:   "launcher",
+			Name:        pbConstants.DroppedIterationCount,
+			Help:        "the number of bots an executor couldn't start because it was at max concurrency",
+			ConstLabels: constLabels,
+		},
+		[]string{"spec"},
+	)
+
+	p.gaugeReportersMap[pbConstants.TargetBots] = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace:   fmt.Sprintf("pitaya_bot_%s", p.game),
+			Subsystem:   "launcher",
+			Name:        pbConstants.TargetBots,
+			Help:        "the number of bots a ramping executor is aiming for",
+			ConstLabels: constLabels,
+		},
+		[]string{"spec"},
+	)
+
+	p.gaugeReportersMap[pbConstants.ActiveBots] = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace:   fmt.Sprintf("pitaya_bot_%s", p.game),
+			Subsystem:   "launcher",
+			Name:        pbConstants.ActiveBots,
+			Help:        "the number of bots running each spec",
+			ConstLabels: constLabels,
+		},
+		[]string{"spec"},
+	)
+
+	p.countReportersMap[pbConstants.RetryCount] = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace:   fmt.Sprintf("pitaya_bot_%s", p.game),
+			Subsystem:   "handler",
+			Name:        pbConstants.RetryCount,
+			Help:        "the number of times a failed request was retried",
+			ConstLabels: constLabels,
+		},
+		[]string{"route", "reason"},
+	)
+
+	p.countReportersMap[pbConstants.PushDroppedCount] = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace:   fmt.Sprintf("pitaya_bot_%s", p.game),
+			Subsystem:   "bot",
+			Name:        pbConstants.PushDroppedCount,
+			Help:        "the number of pushes dropped because their route queue was full",
+			ConstLabels: constLabels,
+		},
+		[]string{"route"},
+	)
+
+	p.countReportersMap[pbConstants.PushUnconsumedCount] = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace:   fmt.Sprintf("pitaya_bot_%s", p.game),
+			Subsystem:   "bot",
+			Name:        pbConstants.PushUnconsumedCount,
+			Help:        "the number of pushes never listened to before the client disconnected",
+			ConstLabels: constLabels,
+		},
+		[]string{"route"},
+	)
+
+	p.summaryReportersMap[pbConstants.PushWaitTime] = prometheus.NewSummaryVec(
+		prometheus.SummaryOpts{
+			Namespace:   fmt.Sprintf("pitaya_bot_%s", p.game),
+			Subsystem:   "bot",
+			Name:        pbConstants.PushWaitTime,
+			Help:        "the time listen operations waited for a push in milliseconds",
+			Objectives:  map[float64]float64{0.7: 0.02, 0.95: 0.005, 0.99: 0.001},
+			ConstLabels: constLabels,
+		},
+		[]string{"route"},
+	)
+
+	p.countReportersMap[pbConstants.PushTimeoutCount] = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace:   fmt.Sprintf("pitaya_bot_%s", p.game),
+			Subsystem:   "bot",
+			Name:        pbConstants.PushTimeoutCount,
+			Help:        "the number of listen operations that timed out waiting for a push",
+			ConstLabels: constLabels,
+		},
+		[]string{"route"},
+	)
+
+	p.histogramReportersMap[pbConstants.PushWaitTimeHistogram] = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace:   fmt.Sprintf("pitaya_bot_%s", p.game),
+			Subsystem:   "bot",
+			Name:        pbConstants.PushWaitTimeHistogram,
+			Help:        "histogram of the time listen operations waited for a push in milliseconds",
+			Buckets:     buckets,
+			ConstLabels: constLabels,
+		},
+		[]string{"route"},
+	)
+
+	p.countReportersMap[pbConstants.NotifyCount] = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace:   fmt.Sprintf("pitaya_bot_%s", p.game),
+			Subsystem:   "handler",
+			Name:        pbConstants.NotifyCount,
+			Help:        "the number of notifies sent",
+			ConstLabels: constLabels,
+		},
+		[]string{"route"},
+	)
+
+	p.countReportersMap[pbConstants.NotifyErrorCount] = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace:   fmt.Sprintf("pitaya_bot_%s", p.game),
+			Subsystem:   "handler",
+			Name:        pbConstants.NotifyErrorCount,
+			Help:        "the number of notifies that failed to be sent",
+			ConstLabels: constLabels,
+		},
+		[]string{"route"},
+	)
+
+	p.countReportersMap[pbConstants.RequestTimeoutCount] = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace:   fmt.Sprintf("pitaya_bot_%s", p.game),
+			Subsystem:   "handler",
+			Name:        pbConstants.RequestTimeoutCount,
+			Help:        "the number of requests that timed out",
+			ConstLabels: constLabels,
+		},
+		[]string{"route"},
+	)
+
+	p.countReportersMap[pbConstants.ExpectFailureCount] = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace:   fmt.Sprintf("pitaya_bot_%s", p.game),
+			Subsystem:   "bot",
+			Name:        pbConstants.ExpectFailureCount,
+			Help:        "the number of responses and pushes that failed their expectations",
+			ConstLabels: constLabels,
+		},
+		[]string{"route", "kind"},
+	)
+
+	p.countReportersMap[pbConstants.BytesSent] = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace:   fmt.Sprintf("pitaya_bot_%s", p.game),
+			Subsystem:   "bot",
+			Name:        pbConstants.BytesSent,
+			Help:        "the size of the requests and notifies sent in bytes",
+			ConstLabels: constLabels,
+		},
+		[]string{"route"},
+	)
+
+	p.countReportersMap[pbConstants.BytesReceived] = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace:   fmt.Sprintf("pitaya_bot_%s", p.game),
+			Subsystem:   "bot",
+			Name:        pbConstants.BytesReceived,
+			Help:        "the size of the responses and pushes received in bytes",
+			ConstLabels: constLabels,
+		},
+		[]string{"route"},
+	)
+
+	p.countReportersMap[pbConstants.ConnectionAttemptCount] = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace:   fmt.Sprintf("pitaya_bot_%s", p.game),
+			Subsystem:   "bot",
+			Name:        pbConstants.ConnectionAttemptCount,
+			Help:        "the number of times bots tried to connect to the server",
+			ConstLabels: constLabels,
+		},
+		[]string{"spec"},
+	)
+
+	p.countReportersMap[pbConstants.ConnectionFailureCount] = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace:   fmt.Sprintf("pitaya_bot_%s", p.game),
+			Subsystem:   "bot",
+			Name:        pbConstants.ConnectionFailureCount,
+			Help:        "the number of times bots failed to connect to the server",
+			ConstLabels: constLabels,
+		},
+		[]string{"spec"},
+	)
+
+	p.summaryReportersMap[pbConstants.ConnectionTime] = prometheus.NewSummaryVec(
+		prometheus.SummaryOpts{
+			Namespace:   fmt.Sprintf("pitaya_bot_%s", p.game),
+			Subsystem:   "bot",
+			Name:        pbConstants.ConnectionTime,
+			Help:        "the time bots took to connect to the server in milliseconds",
+			Objectives:  map[float64]float64{0.7: 0.02, 0.95: 0.005, 0.99: 0.001},
+			ConstLabels: constLabels,
+		},
+		[]string{"spec"},
+	)
+
+	p.countReportersMap[pbConstants.IterationStartedCount] = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace:   fmt.Sprintf("pitaya_bot_%s", p.game),
+			Subsystem:   "bot",
+			Name:        pbConstants.IterationStartedCount,
+			Help:        "the number of times bots started running a spec",
+			ConstLabels: constLabels,
+		},
+		[]string{"spec"},
+	)
+
+	p.countReportersMap[pbConstants.IterationCompletedCount] = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace:   fmt.Sprintf("pitaya_bot_%s", p.game),
+			Subsystem:   "bot",
+			Name:        pbConstants.IterationCompletedCount,
+			Help:        "the number of times bots finished running a spec",
+			ConstLabels: constLabels,
+		},
+		[]string{"spec"},
+	)
+
+	p.countReportersMap[pbConstants.IterationFailedCount] = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace:   fmt.Sprintf("pitaya_bot_%s", p.game),
+			Subsystem:   "bot",
+			Name:        pbConstants.IterationFailedCount,
+			Help:        "the number of times bots failed running a spec",
+			ConstLabels: constLabels,
+		},
+		[]string{"spec"},
+	)
+
 	toRegister := make([]prometheus.Collector, 0)
 	for _, c := range p.countReportersMap {
 		toRegister = append(toRegister, c)
@@ -77,9 +339,24 @@ func (p *PrometheusReporter) registerMetrics(constLabels map[string]string) {
 		toRegister = append(toRegister, c)
 	}
 
+	for _, c := range p.histogramReportersMap {
+		toRegister = append(toRegister, c)
+	}
+
 	prometheus.MustRegister(toRegister...)
 }
 
+// ValidateBuckets returns an error if the histogram buckets are not strictly
+// increasing, which prometheus refuses
+func ValidateBuckets(buckets []float64) error {
+	for idx := 1; idx < len(buckets); idx++ {
+		if buckets[idx] <= buckets[idx-1] {
+			return fmt.Errorf("histogram buckets must be increasing, got %v after %v", buckets[idx], buckets[idx-1])
+		}
+	}
+	return nil
+}
+
 func metricsReporterHandler(prometheusHandler http.Handler, postHandlerAction func()) http.HandlerFunc {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		prometheusHandler.ServeHTTP(w, r)
@@ -87,8 +364,28 @@ func metricsReporterHandler(prometheusHandler http.Handler, postHandlerAction fu
 	})
 }
 
-// GetPrometheusReporter gets the prometheus reporter singleton
-func GetPrometheusReporter(game string, port int, constLabels map[string]string, postMetricsScrapeAction func()) *PrometheusReporter {
+// GetPrometheusReporter gets the prometheus reporter singleton, its
+// histograms use the given buckets
+func GetPrometheusReporter(game string, port int, constLabels map[string]string, buckets []float64, postMetricsScrapeAction func()) *PrometheusReporter {
+	reporter := getPrometheusReporter(game, constLabels, buckets)
+	serveOnce.Do(func() {
+		http.Handle("/metrics", metricsReporterHandler(prometheus.Handler(), postMetricsScrapeAction))
+
+		go (func() {
+			log.Printf("Running prometheus on port %d for game %s", port, game)
+			log.Fatal(http.ListenAndServe(fmt.Sprintf(":%d", port), nil))
+		})()
+	})
+	return reporter
+}
+
+// GetPrometheusPushReporter gets the prometheus reporter singleton without
+// serving /metrics, its metrics are sent by a PrometheusPusher instead
+func GetPrometheusPushReporter(game string, constLabels map[string]string, buckets []float64) *PrometheusReporter {
+	return getPrometheusReporter(game, constLabels, buckets)
+}
+
+func getPrometheusReporter(game string, constLabels map[string]string, buckets []float64) *PrometheusReporter {
 	once.Do(func() {
 		prometheusReporter = &PrometheusReporter{
 			game:                  game,
@@ -98,13 +395,7 @@ func GetPrometheusReporter(game string, port int, constLabels map[string]string,
 			gaugeReportersMap:     make(map[string]*prometheus.GaugeVec),
 		}
 
-		prometheusReporter.registerMetrics(constLabels)
-		http.Handle("/metrics", metricsReporterHandler(prometheus.Handler(), postMetricsScrapeAction))
-
-		go (func() {
-			log.Printf("Running prometheus on port %d for game %s", port, game)
-			log.Fatal(http.ListenAndServe(fmt.Sprintf(":%d", port), nil))
-		})()
+		prometheusReporter.registerMetrics(constLabels, buckets)
 	})
 	return prometheusReporter
 }
@@ -120,7 +411,7 @@ func (p *PrometheusReporter) ReportSummary(metric string, labels map[string]stri
 	return constants.ErrMetricNotKnown
 }
 
-// ReportHistogram reports a summary metric
+// ReportHistogram reports a histogram metric
 //  - implements the ReportHistogram method of the Reporter interface
 func (p *PrometheusReporter) ReportHistogram(metric string, labels map[string]string, value float64) error {
 	sum := p.histogramReportersMap[metric]
@@ -131,7 +422,7 @@ func (p *PrometheusReporter) ReportHistogram(metric string, labels map[string]st
 	return constants.ErrMetricNotKnown
 }
 
-// ReportCount reports a summary metric
+// ReportCount reports a count metric
 //  - implements the ReportCount method of the Reporter interface
 func (p *PrometheusReporter) ReportCount(metric string, labels map[string]string, count float64) error {
 	cnt := p.countReportersMap[metric]
@@ -0,0 +1,60 @@
+package metrics
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	pbConstants "github.com/topfreegames/pitaya-bot/constants"
+)
+
+func TestValidateBuckets(t *testing.T) {
+	var validateBucketsTable = map[string]struct {
+		buckets []float64
+		err     error
+	}{
+		"default":    {DefaultBuckets, nil},
+		"single":     {[]float64{100}, nil},
+		"decreasing": {[]float64{10, 5}, errors.New("histogram buckets must be increasing, got 5 after 10")},
+		"repeated":   {[]float64{10, 10}, errors.New("histogram buckets must be increasing, got 10 after 10")},
+	}
+
+	for name, table := range validateBucketsTable {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, table.err, ValidateBuckets(table.buckets))
+		})
+	}
+}
+
+func TestPrometheusReporterRegistersMetrics(t *testing.T) {
+	reporter := GetPrometheusPushReporter("test", map[string]string{}, DefaultBuckets)
+	route := map[string]string{"route": "room.join"}
+	spec := map[string]string{"spec": "specs/login.json"}
+
+	assert.NoError(t, reporter.ReportHistogram(pbConstants.ResponseTimeHistogram, route, 12))
+	assert.NoError(t, reporter.ReportHistogram(pbConstants.PushWaitTimeHistogram, route, 30))
+	assert.NoError(t, reporter.ReportCount(pbConstants.BytesReceived, route, 42))
+	assert.NoError(t, reporter.ReportSummary(pbConstants.ConnectionTime, spec, 5))
+	assert.NoError(t, reporter.ReportCount(pbConstants.IterationCompletedCount, spec, 1))
+	assert.NoError(t, reporter.ReportGauge(pbConstants.ActiveBots, spec, 3))
+	assert.Error(t, reporter.ReportHistogram("unknown", route, 1))
+
+	families, err := prometheus.DefaultGatherer.Gather()
+	assert.NoError(t, err)
+	names := make(map[string]bool)
+	for _, family := range families {
+		names[family.GetName()] = true
+	}
+
+	for _, name := range []string{
+		"pitaya_bot_test_handler_" + pbConstants.ResponseTimeHistogram,
+		"pitaya_bot_test_bot_" + pbConstants.PushWaitTimeHistogram,
+		"pitaya_bot_test_bot_" + pbConstants.BytesReceived,
+		"pitaya_bot_test_bot_" + pbConstants.ConnectionTime,
+		"pitaya_bot_test_bot_" + pbConstants.IterationCompletedCount,
+		"pitaya_bot_test_launcher_" + pbConstants.ActiveBots,
+	} {
+		assert.Contains(t, names, name)
+	}
+}
@@ -1,5 +1,7 @@
 package metrics
 
+import "github.com/sirupsen/logrus"
+
 // Reporter interface
 type Reporter interface {
 	ReportCount(metric string, tags map[string]string, count float64) error
@@ -7,3 +9,43 @@ type Reporter interface {
 	ReportHistogram(metric string, tags map[string]string, value float64) error
 	ReportGauge(metric string, tags map[string]string, value float64) error
 }
+
+// ReportCount adds count to the metric of every reporter, logging the
+// reporters that fail
+func ReportCount(reporters []Reporter, metric string, tags map[string]string, count float64, logger logrus.FieldLogger) {
+	for _, mr := range reporters {
+		if err := mr.ReportCount(metric, tags, count); err != nil {
+			logger.WithError(err).Error("Failed to Report Count")
+		}
+	}
+}
+
+// ReportSummary observes value in the summary metric of every reporter,
+// logging the reporters that fail
+func ReportSummary(reporters []Reporter, metric string, tags map[string]string, value float64, logger logrus.FieldLogger) {
+	for _, mr := range reporters {
+		if err := mr.ReportSummary(metric, tags, value); err != nil {
+			logger.WithError(err).Error("Failed to Report Summary")
+		}
+	}
+}
+
+// ReportHistogram observes value in the histogram metric of every reporter,
+// logging the reporters that fail
+func ReportHistogram(reporters []Reporter, metric string, tags map[string]string, value float64, logger logrus.FieldLogger) {
+	for _, mr := range reporters {
+		if err := mr.ReportHistogram(metric, tags, value); err != nil {
+			logger.WithError(err).Error("Failed to Report Histogram")
+		}
+	}
+}
+
+// ReportGauge sets the gauge metric of every reporter to value, logging the
+// reporters that fail
+func ReportGauge(reporters []Reporter, metric string, tags map[string]string, value float64, logger logrus.FieldLogger) {
+	for _, mr := range reporters {
+		if err := mr.ReportGauge(metric, tags, value); err != nil {
+			logger.WithError(err).Error("Failed to Report Gauge")
+		}
+	}
+}
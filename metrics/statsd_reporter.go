@@ -0,0 +1,171 @@
+package metrics
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// statsdErrorInterval is how often write errors are returned for each
+// metric. The errors in between are counted and dropped, so an unreachable
+// server doesn't flood the logs
+const statsdErrorInterval = 10 * time.Second
+
+// StatsdReporter pushes metrics to a StatsD server over UDP, so short lived
+// bots don't have to wait for a scrape. With DogStatsD the labels are sent
+// as tags, otherwise they are appended to the metric name
+type StatsdReporter struct {
+	conn      net.Conn
+	prefix    string
+	dogstatsd bool
+	tags      map[string]string
+
+	mutex         sync.Mutex
+	errors        map[string]*statsdErrors
+	errorInterval time.Duration
+}
+
+// statsdErrors tracks the write errors of a metric
+type statsdErrors struct {
+	last    time.Time
+	dropped int
+}
+
+// NewStatsdReporter returns a reporter sending metrics to the StatsD server
+// at address, such as localhost:8125. The metric names start with prefix and
+// constTags are added to every metric
+func NewStatsdReporter(address, prefix string, dogstatsd bool, constTags map[string]string) (*StatsdReporter, error) {
+	conn, err := net.Dial("udp", address)
+	if err != nil {
+		return nil, err
+	}
+
+	return &StatsdReporter{
+		conn:          conn,
+		prefix:        prefix,
+		dogstatsd:     dogstatsd,
+		tags:          constTags,
+		errors:        map[string]*statsdErrors{},
+		errorInterval: statsdErrorInterval,
+	}, nil
+}
+
+// ReportSummary sends a timing metric
+//  - implements the ReportSummary method of the Reporter interface
+func (s *StatsdReporter) ReportSummary(metric string, labels map[string]string, value float64) error {
+	return s.send(metric, labels, value, "ms")
+}
+
+// ReportHistogram sends a histogram metric with DogStatsD, or a timing
+// metric otherwise
+//  - implements the ReportHistogram method of the Reporter interface
+func (s *StatsdReporter) ReportHistogram(metric string, labels map[string]string, value float64) error {
+	if s.dogstatsd {
+		return s.send(metric, labels, value, "h")
+	}
+	return s.send(metric, labels, value, "ms")
+}
+
+// ReportCount sends a counter metric
+//  - implements the ReportCount method of the Reporter interface
+func (s *StatsdReporter) ReportCount(metric string, labels map[string]string, count float64) error {
+	return s.send(metric, labels, count, "c")
+}
+
+// ReportGauge sends a gauge metric
+//  - implements the ReportGauge method of the Reporter interface
+func (s *StatsdReporter) ReportGauge(metric string, labels map[string]string, value float64) error {
+	return s.send(metric, labels, value, "g")
+}
+
+// Close closes the connection to the server
+func (s *StatsdReporter) Close() error {
+	return s.conn.Close()
+}
+
+func (s *StatsdReporter) send(metric string, labels map[string]string, value float64, metricType string) error {
+	if _, err := s.conn.Write([]byte(s.format(metric, labels, value, metricType))); err != nil {
+		return s.throttle(metric, err)
+	}
+	return nil
+}
+
+// throttle returns err at most once per errorInterval for each metric,
+// with the number of errors dropped since the last one returned
+func (s *StatsdReporter) throttle(metric string, err error) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	e, ok := s.errors[metric]
+	if !ok {
+		e = &statsdErrors{}
+		s.errors[metric] = e
+	}
+	now := time.Now()
+	if now.Sub(e.last) < s.errorInterval {
+		e.dropped++
+		return nil
+	}
+
+	e.last = now
+	if e.dropped > 0 {
+		err = fmt.Errorf("%s (%d errors dropped since the last one)", err, e.dropped)
+		e.dropped = 0
+	}
+	return err
+}
+
+// format returns the StatsD line of the metric, such as
+// pitaya_bot.response_time_ms:12|ms|#game:tennis,route:room.join
+func (s *StatsdReporter) format(metric string, labels map[string]string, value float64, metricType string) string {
+	tags := make(map[string]string, len(s.tags)+len(labels))
+	for k, v := range s.tags {
+		tags[k] = v
+	}
+	for k, v := range labels {
+		tags[k] = v
+	}
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	name := s.prefix + metric
+	formatted := strconv.FormatFloat(value, 'f', -1, 64)
+	if !s.dogstatsd {
+		for _, k := range keys {
+			name += "." + sanitizeGraphite(k) + "." + sanitizeGraphite(tags[k])
+		}
+		return fmt.Sprintf("%s:%s|%s", name, formatted, metricType)
+	}
+
+	pairs := make([]string, len(keys))
+	for idx, k := range keys {
+		pairs[idx] = sanitizeStatsd(k) + ":" + sanitizeStatsd(tags[k])
+	}
+	line := fmt.Sprintf("%s:%s|%s", name, formatted, metricType)
+	if len(pairs) > 0 {
+		line += "|#" + strings.Join(pairs, ",")
+	}
+	return line
+}
+
+// statsdReplacer replaces the characters with a meaning in StatsD lines
+var statsdReplacer = strings.NewReplacer(":", "_", "|", "_", "#", "_", ",", "_", "@", "_", " ", "_")
+
+func sanitizeStatsd(s string) string {
+	return statsdReplacer.Replace(s)
+}
+
+// graphiteReplacer replaces the characters that would add levels to the
+// metric hierarchy when labels are appended to the name
+var graphiteReplacer = strings.NewReplacer(".", "_", "/", "_")
+
+func sanitizeGraphite(s string) string {
+	return graphiteReplacer.Replace(sanitizeStatsd(s))
+}
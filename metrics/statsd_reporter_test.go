@@ -0,0 +1,71 @@
+package metrics
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStatsdReporterFormat(t *testing.T) {
+	var formatTable = map[string]struct {
+		dogstatsd bool
+		report    func(r *StatsdReporter) error
+		line      string
+	}{
+		"summary": {true, func(r *StatsdReporter) error {
+			return r.ReportSummary("response_time_ms", map[string]string{"route": "room.join"}, 12.5)
+		}, "pitaya_bot.response_time_ms:12.5|ms|#game:tennis,route:room.join"},
+		"count": {true, func(r *StatsdReporter) error {
+			return r.ReportCount("retry_count", map[string]string{"route": "room.join", "reason": "timeout"}, 1)
+		}, "pitaya_bot.retry_count:1|c|#game:tennis,reason:timeout,route:room.join"},
+		"gauge": {true, func(r *StatsdReporter) error {
+			return r.ReportGauge("active_bots", map[string]string{"spec": "specs/a b.json"}, 10)
+		}, "pitaya_bot.active_bots:10|g|#game:tennis,spec:specs/a_b.json"},
+		"histogram": {true, func(r *StatsdReporter) error {
+			return r.ReportHistogram("response_time_histogram_ms", nil, 3)
+		}, "pitaya_bot.response_time_histogram_ms:3|h|#game:tennis"},
+		"plain_statsd": {false, func(r *StatsdReporter) error {
+			return r.ReportHistogram("response_time_histogram_ms", map[string]string{"route": "room.join"}, 3)
+		}, "pitaya_bot.response_time_histogram_ms.game.tennis.route.room_join:3|ms"},
+		"plain_statsd_spec": {false, func(r *StatsdReporter) error {
+			return r.ReportGauge("active_bots", map[string]string{"spec": "specs/login.json"}, 10)
+		}, "pitaya_bot.active_bots.game.tennis.spec.specs_login_json:10|g"},
+	}
+
+	for name, table := range formatTable {
+		t.Run(name, func(t *testing.T) {
+			listener, err := net.ListenPacket("udp", "127.0.0.1:0")
+			assert.NoError(t, err)
+			defer listener.Close()
+
+			reporter, err := NewStatsdReporter(listener.LocalAddr().String(), "pitaya_bot.", table.dogstatsd, map[string]string{"game": "tennis"})
+			assert.NoError(t, err)
+			defer reporter.Close()
+
+			assert.NoError(t, table.report(reporter))
+
+			buf := make([]byte, 512)
+			listener.SetReadDeadline(time.Now().Add(time.Second))
+			n, _, err := listener.ReadFrom(buf)
+			assert.NoError(t, err)
+			assert.Equal(t, table.line, string(buf[:n]))
+		})
+	}
+}
+
+func TestStatsdReporterThrottle(t *testing.T) {
+	reporter := &StatsdReporter{errors: map[string]*statsdErrors{}, errorInterval: 50 * time.Millisecond}
+	err := errors.New("connection refused")
+
+	assert.Equal(t, err, reporter.throttle("retry_count", err))
+	assert.NoError(t, reporter.throttle("retry_count", err))
+	assert.NoError(t, reporter.throttle("retry_count", err))
+	// each metric is throttled on its own
+	assert.Equal(t, err, reporter.throttle("active_bots", err))
+
+	time.Sleep(60 * time.Millisecond)
+	assert.EqualError(t, reporter.throttle("retry_count", err), "connection refused (2 errors dropped since the last one)")
+}
@@ -0,0 +1,229 @@
+package metrics
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	pbConstants "github.com/topfreegames/pitaya-bot/constants"
+)
+
+// Kinds of routes in the summary
+const (
+	SummaryKindRequest = "request"
+	SummaryKindPush    = "push"
+	SummaryKindNotify  = "notify"
+)
+
+// RouteStats are the statistics of a route at the end of a run, latencies
+// are in milliseconds. Errors include the expectation failures
+type RouteStats struct {
+	Kind           string  `json:"kind"`
+	Route          string  `json:"route"`
+	Count          int     `json:"count"`
+	Errors         int     `json:"errors"`
+	ExpectFailures int     `json:"expectFailures"`
+	Min            float64 `json:"min"`
+	Mean           float64 `json:"mean"`
+	P50            float64 `json:"p50"`
+	P90            float64 `json:"p90"`
+	P95            float64 `json:"p95"`
+	P99            float64 `json:"p99"`
+	Max            float64 `json:"max"`
+	Throughput     float64 `json:"throughput"`
+}
+
+// Summary is the report of a run
+type Summary struct {
+	Duration float64       `json:"duration"`
+	Routes   []*RouteStats `json:"routes"`
+}
+
+type summaryKey struct {
+	kind  string
+	route string
+}
+
+// SummaryReporter keeps the latencies and errors of each route in process, so
+// a summary can be printed at the end of the run without any other reporter.
+// Latencies are kept in histograms, so memory doesn't grow with the run
+type SummaryReporter struct {
+	mutex          sync.Mutex
+	latencies      map[summaryKey]*latencyHistogram
+	counts         map[summaryKey]int
+	errors         map[summaryKey]int
+	expectFailures map[summaryKey]int
+}
+
+// NewSummaryReporter returns a new summary reporter
+func NewSummaryReporter() *SummaryReporter {
+	return &SummaryReporter{
+		latencies:      make(map[summaryKey]*latencyHistogram),
+		counts:         make(map[summaryKey]int),
+		errors:         make(map[summaryKey]int),
+		expectFailures: make(map[summaryKey]int),
+	}
+}
+
+// latencyKinds maps the summary metrics kept by the reporter to their kind
+var latencyKinds = map[string]string{
+	pbConstants.ResponseTime: SummaryKindRequest,
+	pbConstants.PushWaitTime: SummaryKindPush,
+}
+
+// countKinds maps the count metrics of routes without latencies, such as
+// notifies, to their kind
+var countKinds = map[string]string{
+	pbConstants.NotifyCount: SummaryKindNotify,
+}
+
+// errorKinds maps the count metrics kept by the reporter to their kind
+var errorKinds = map[string]string{
+	pbConstants.ErrorCount:       SummaryKindRequest,
+	pbConstants.PushTimeoutCount: SummaryKindPush,
+	pbConstants.NotifyErrorCount: SummaryKindNotify,
+}
+
+// ReportSummary keeps the latency of a route
+//  - implements the ReportSummary method of the Reporter interface
+func (s *SummaryReporter) ReportSummary(metric string, labels map[string]string, value float64) error {
+	kind, ok := latencyKinds[metric]
+	if !ok {
+		return nil
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	key := summaryKey{kind: kind, route: labels["route"]}
+	if s.latencies[key] == nil {
+		s.latencies[key] = newLatencyHistogram()
+	}
+	s.latencies[key].add(value)
+	return nil
+}
+
+// ReportHistogram is a no-op, latencies are kept from summaries
+//  - implements the ReportHistogram method of the Reporter interface
+func (s *SummaryReporter) ReportHistogram(metric string, labels map[string]string, value float64) error {
+	return nil
+}
+
+// ReportCount keeps the errors of a route. Expectation failures are kept
+// apart, under the kind they are labeled with, and also count as errors
+//  - implements the ReportCount method of the Reporter interface
+func (s *SummaryReporter) ReportCount(metric string, labels map[string]string, count float64) error {
+	if metric == pbConstants.ExpectFailureCount {
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+		s.expectFailures[summaryKey{kind: labels["kind"], route: labels["route"]}] += int(count)
+		return nil
+	}
+
+	if kind, ok := countKinds[metric]; ok {
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+		s.counts[summaryKey{kind: kind, route: labels["route"]}] += int(count)
+		return nil
+	}
+
+	kind, ok := errorKinds[metric]
+	if !ok {
+		return nil
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.errors[summaryKey{kind: kind, route: labels["route"]}] += int(count)
+	return nil
+}
+
+// ReportGauge is a no-op
+//  - implements the ReportGauge method of the Reporter interface
+func (s *SummaryReporter) ReportGauge(metric string, labels map[string]string, value float64) error {
+	return nil
+}
+
+// Summary returns the stats of every route reported so far, sorted by kind
+// and route. Throughput is computed over elapsed
+func (s *SummaryReporter) Summary(elapsed time.Duration) *Summary {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	keys := make(map[summaryKey]bool)
+	for key := range s.latencies {
+		keys[key] = true
+	}
+	for key := range s.counts {
+		keys[key] = true
+	}
+	for key := range s.errors {
+		keys[key] = true
+	}
+	for key := range s.expectFailures {
+		keys[key] = true
+	}
+
+	summary := &Summary{Duration: elapsed.Seconds(), Routes: make([]*RouteStats, 0, len(keys))}
+	for key := range keys {
+		stats := newRouteStats(s.latencies[key])
+		stats.Kind, stats.Route = key.kind, key.route
+		if count, ok := s.counts[key]; ok {
+			stats.Count = count
+		}
+		stats.ExpectFailures = s.expectFailures[key]
+		stats.Errors = s.errors[key] + stats.ExpectFailures
+		if elapsed > 0 {
+			stats.Throughput = float64(stats.Count) / elapsed.Seconds()
+		}
+		summary.Routes = append(summary.Routes, stats)
+	}
+
+	sort.Slice(summary.Routes, func(i, j int) bool {
+		if summary.Routes[i].Kind != summary.Routes[j].Kind {
+			return summary.Routes[i].Kind > summary.Routes[j].Kind
+		}
+		return summary.Routes[i].Route < summary.Routes[j].Route
+	})
+	return summary
+}
+
+// newRouteStats returns the stats of the latencies, which may be nil.
+// Percentiles are estimated within 1%, while min, mean and max are exact
+func newRouteStats(latencies *latencyHistogram) *RouteStats {
+	if latencies == nil || latencies.count == 0 {
+		return &RouteStats{}
+	}
+
+	return &RouteStats{
+		Count: latencies.count,
+		Min:   latencies.min,
+		Mean:  latencies.sum / float64(latencies.count),
+		P50:   latencies.percentile(0.5),
+		P90:   latencies.percentile(0.9),
+		P95:   latencies.percentile(0.95),
+		P99:   latencies.percentile(0.99),
+		Max:   latencies.max,
+	}
+}
+
+// WriteTable writes the summary as a table aligned in columns
+func (s *Summary) WriteTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "KIND\tROUTE\tCOUNT\tERRORS\tEXPECT\tMIN\tMEAN\tP50\tP90\tP95\tP99\tMAX\tRATE/S")
+	for _, r := range s.Routes {
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%d\t%d\t%.2f\t%.2f\t%.2f\t%.2f\t%.2f\t%.2f\t%.2f\t%.2f\n",
+			r.Kind, r.Route, r.Count, r.Errors, r.ExpectFailures, r.Min, r.Mean, r.P50, r.P90, r.P95, r.P99, r.Max, r.Throughput)
+	}
+	return tw.Flush()
+}
+
+// WriteJSON writes the summary as indented JSON
+func (s *Summary) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(s)
+}
@@ -0,0 +1,78 @@
+package metrics
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	pbConstants "github.com/topfreegames/pitaya-bot/constants"
+)
+
+func TestSummaryReporter(t *testing.T) {
+	r := NewSummaryReporter()
+	login := map[string]string{"route": "connector.login"}
+	for i := 100; i >= 1; i-- {
+		assert.NoError(t, r.ReportSummary(pbConstants.ResponseTime, login, float64(i)))
+	}
+	assert.NoError(t, r.ReportCount(pbConstants.ErrorCount, login, 2))
+	assert.NoError(t, r.ReportSummary(pbConstants.PushWaitTime, map[string]string{"route": "room.start"}, 30))
+	assert.NoError(t, r.ReportCount(pbConstants.PushTimeoutCount, map[string]string{"route": "room.start,room.end"}, 1))
+	assert.NoError(t, r.ReportCount(pbConstants.NotifyCount, map[string]string{"route": "room.chat"}, 5))
+	assert.NoError(t, r.ReportCount(pbConstants.NotifyErrorCount, map[string]string{"route": "room.chat"}, 1))
+	assert.NoError(t, r.ReportCount(pbConstants.ExpectFailureCount, map[string]string{"route": "connector.login", "kind": SummaryKindRequest}, 3))
+
+	// metrics the summary doesn't use are ignored
+	assert.NoError(t, r.ReportCount(pbConstants.RetryCount, login, 1))
+	assert.NoError(t, r.ReportSummary("unknown", login, 1))
+	assert.NoError(t, r.ReportGauge(pbConstants.ActiveBots, nil, 1))
+	assert.NoError(t, r.ReportHistogram(pbConstants.ResponseTimeHistogram, login, 1))
+
+	summary := r.Summary(10 * time.Second)
+	assert.Equal(t, 10.0, summary.Duration)
+	assert.Len(t, summary.Routes, 4)
+
+	// percentiles are estimated from buckets, the other stats are exact
+	stats := summary.Routes[0]
+	assert.Equal(t, SummaryKindRequest, stats.Kind)
+	assert.Equal(t, "connector.login", stats.Route)
+	assert.Equal(t, 100, stats.Count)
+	assert.Equal(t, 5, stats.Errors)
+	assert.Equal(t, 3, stats.ExpectFailures)
+	assert.Equal(t, 1.0, stats.Min)
+	assert.Equal(t, 50.5, stats.Mean)
+	assert.Equal(t, 100.0, stats.Max)
+	assert.Equal(t, 10.0, stats.Throughput)
+	assert.InEpsilon(t, 50, stats.P50, 0.01)
+	assert.InEpsilon(t, 90, stats.P90, 0.01)
+	assert.InEpsilon(t, 95, stats.P95, 0.01)
+	assert.InEpsilon(t, 99, stats.P99, 0.01)
+
+	assert.Equal(t, &RouteStats{
+		Kind: SummaryKindPush, Route: "room.start", Count: 1,
+		Min: 30, Mean: 30, P50: 30, P90: 30, P95: 30, P99: 30, Max: 30, Throughput: 0.1,
+	}, summary.Routes[1])
+	assert.Equal(t, &RouteStats{Kind: SummaryKindPush, Route: "room.start,room.end", Errors: 1}, summary.Routes[2])
+	assert.Equal(t, &RouteStats{Kind: SummaryKindNotify, Route: "room.chat", Count: 5, Errors: 1, Throughput: 0.5}, summary.Routes[3])
+}
+
+func TestSummaryWrite(t *testing.T) {
+	summary := &Summary{Duration: 2, Routes: []*RouteStats{
+		{Kind: SummaryKindRequest, Route: "connector.login", Count: 4, Min: 1, Mean: 2.5, P50: 2, P90: 4, P95: 4, P99: 4, Max: 4, Throughput: 2},
+	}}
+
+	var table bytes.Buffer
+	assert.NoError(t, summary.WriteTable(&table))
+	lines := strings.Split(strings.TrimSpace(table.String()), "\n")
+	assert.Len(t, lines, 2)
+	assert.Equal(t, []string{"KIND", "ROUTE", "COUNT", "ERRORS", "EXPECT", "MIN", "MEAN", "P50", "P90", "P95", "P99", "MAX", "RATE/S"}, strings.Fields(lines[0]))
+	assert.Equal(t, []string{"request", "connector.login", "4", "0", "0", "1.00", "2.50", "2.00", "4.00", "4.00", "4.00", "4.00", "2.00"}, strings.Fields(lines[1]))
+
+	var raw bytes.Buffer
+	assert.NoError(t, summary.WriteJSON(&raw))
+	var decoded Summary
+	assert.NoError(t, json.Unmarshal(raw.Bytes(), &decoded))
+	assert.Equal(t, summary, &decoded)
+}
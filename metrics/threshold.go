@@ -0,0 +1,138 @@
+package metrics
+
+import (
+	"fmt"
+	"time"
+)
+
+// Threshold is a bound on one of the stats of a route in the summary. The
+// run fails if it is crossed
+type Threshold struct {
+	// Route is the route whose stats are checked
+	Route string `mapstructure:"route"`
+	// Kind is the kind of the route, request by default
+	Kind string `mapstructure:"kind"`
+	// Metric is the stat checked: count, errors, expectFailures, errorRate,
+	// min, mean, p50, p90, p95, p99, max or throughput
+	Metric string   `mapstructure:"metric"`
+	Min    *float64 `mapstructure:"min"`
+	Max    *float64 `mapstructure:"max"`
+	// AbortOnFail stops the run as soon as the threshold is crossed, once
+	// the run lasted AbortAfter
+	AbortOnFail bool   `mapstructure:"abortOnFail"`
+	AbortAfter  string `mapstructure:"abortAfter"`
+}
+
+// ThresholdResult is the outcome of a threshold at the end of the run
+type ThresholdResult struct {
+	Threshold *Threshold
+	Value     float64
+	// NoData is true if the route was never reported
+	NoData bool
+	Passed bool
+}
+
+// thresholdMetrics returns the value of each metric of the route stats
+var thresholdMetrics = map[string]func(r *RouteStats) float64{
+	"count":          func(r *RouteStats) float64 { return float64(r.Count) },
+	"errors":         func(r *RouteStats) float64 { return float64(r.Errors) },
+	"expectFailures": func(r *RouteStats) float64 { return float64(r.ExpectFailures) },
+	"errorRate":      func(r *RouteStats) float64 { return errorRate(r) },
+	"min":            func(r *RouteStats) float64 { return r.Min },
+	"mean":           func(r *RouteStats) float64 { return r.Mean },
+	"p50":            func(r *RouteStats) float64 { return r.P50 },
+	"p90":            func(r *RouteStats) float64 { return r.P90 },
+	"p95":            func(r *RouteStats) float64 { return r.P95 },
+	"p99":            func(r *RouteStats) float64 { return r.P99 },
+	"max":            func(r *RouteStats) float64 { return r.Max },
+	"throughput":     func(r *RouteStats) float64 { return r.Throughput },
+}
+
+func errorRate(r *RouteStats) float64 {
+	if r.Count == 0 {
+		return 0
+	}
+	return float64(r.Errors) / float64(r.Count)
+}
+
+// GetKind returns the kind of the route, request if it is not set
+func (t *Threshold) GetKind() string {
+	if t.Kind == "" {
+		return SummaryKindRequest
+	}
+	return t.Kind
+}
+
+// GetAbortAfter returns how long the run must last before the threshold may
+// abort it, zero if it is not set
+func (t *Threshold) GetAbortAfter() time.Duration {
+	d, _ := time.ParseDuration(t.AbortAfter)
+	return d
+}
+
+// Validate returns an error if the threshold is malformed
+func (t *Threshold) Validate() error {
+	if t.Route == "" {
+		return fmt.Errorf("threshold has no route")
+	}
+	if kind := t.GetKind(); kind != SummaryKindRequest && kind != SummaryKindPush && kind != SummaryKindNotify {
+		return fmt.Errorf("threshold on %s has unknown kind %s", t.Route, kind)
+	}
+	if _, ok := thresholdMetrics[t.Metric]; !ok {
+		return fmt.Errorf("threshold on %s has unknown metric %s", t.Route, t.Metric)
+	}
+	if t.Min == nil && t.Max == nil {
+		return fmt.Errorf("threshold on %s %s has neither min nor max", t.Route, t.Metric)
+	}
+	if t.AbortAfter != "" {
+		if d, err := time.ParseDuration(t.AbortAfter); err != nil || d < 0 {
+			return fmt.Errorf("threshold on %s %s has invalid abortAfter %s", t.Route, t.Metric, t.AbortAfter)
+		}
+	}
+	return nil
+}
+
+// Check evaluates the threshold against the summary. A route missing from
+// the summary fails the threshold
+func (t *Threshold) Check(summary *Summary) *ThresholdResult {
+	result := &ThresholdResult{Threshold: t, NoData: true}
+	for _, r := range summary.Routes {
+		if r.Kind == t.GetKind() && r.Route == t.Route {
+			result.Value = thresholdMetrics[t.Metric](r)
+			result.NoData = false
+			break
+		}
+	}
+
+	result.Passed = !result.NoData &&
+		(t.Min == nil || result.Value >= *t.Min) &&
+		(t.Max == nil || result.Value <= *t.Max)
+	return result
+}
+
+// String describes the threshold, such as request connector.login p95 <= 200
+func (t *Threshold) String() string {
+	bounds := ""
+	if t.Min != nil {
+		bounds += fmt.Sprintf(" >= %g", *t.Min)
+	}
+	if t.Max != nil {
+		bounds += fmt.Sprintf(" <= %g", *t.Max)
+	}
+	return fmt.Sprintf("%s %s %s%s", t.GetKind(), t.Route, t.Metric, bounds)
+}
+
+// ShouldAbort returns true if a threshold marked to abort on fail is crossed
+// by the partial summary of a run that lasted elapsed. Routes not reported
+// yet don't abort the run
+func ShouldAbort(thresholds []*Threshold, summary *Summary, elapsed time.Duration) (*ThresholdResult, bool) {
+	for _, t := range thresholds {
+		if !t.AbortOnFail || elapsed < t.GetAbortAfter() {
+			continue
+		}
+		if result := t.Check(summary); !result.NoData && !result.Passed {
+			return result, true
+		}
+	}
+	return nil, false
+}
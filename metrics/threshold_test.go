@@ -0,0 +1,87 @@
+package metrics
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func bound(v float64) *float64 {
+	return &v
+}
+
+func TestThresholdValidate(t *testing.T) {
+	var validateTable = map[string]struct {
+		threshold *Threshold
+		err       error
+	}{
+		"valid":          {&Threshold{Route: "connector.login", Metric: "p95", Max: bound(200)}, nil},
+		"valid_push":     {&Threshold{Route: "room.start", Kind: SummaryKindPush, Metric: "errors", Min: bound(0), AbortAfter: "10s"}, nil},
+		"no_route":       {&Threshold{Metric: "p95", Max: bound(200)}, errors.New("threshold has no route")},
+		"unknown_kind":   {&Threshold{Route: "connector.login", Kind: "connect", Metric: "p95", Max: bound(200)}, errors.New("threshold on connector.login has unknown kind connect")},
+		"unknown_metric": {&Threshold{Route: "connector.login", Metric: "p75", Max: bound(200)}, errors.New("threshold on connector.login has unknown metric p75")},
+		"no_bounds":      {&Threshold{Route: "connector.login", Metric: "p95"}, errors.New("threshold on connector.login p95 has neither min nor max")},
+		"abort_after":    {&Threshold{Route: "connector.login", Metric: "p95", Max: bound(200), AbortAfter: "soon"}, errors.New("threshold on connector.login p95 has invalid abortAfter soon")},
+	}
+
+	for name, table := range validateTable {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, table.err, table.threshold.Validate())
+		})
+	}
+}
+
+func TestThresholdCheck(t *testing.T) {
+	summary := &Summary{Duration: 10, Routes: []*RouteStats{
+		{Kind: SummaryKindRequest, Route: "connector.login", Count: 200, Errors: 4, ExpectFailures: 3, P95: 180},
+		{Kind: SummaryKindPush, Route: "room.start", Count: 10, Errors: 0, Throughput: 1},
+	}}
+
+	var checkTable = map[string]struct {
+		threshold *Threshold
+		value     float64
+		noData    bool
+		passed    bool
+	}{
+		"p95_passed":        {&Threshold{Route: "connector.login", Metric: "p95", Max: bound(200)}, 180, false, true},
+		"error_rate_failed": {&Threshold{Route: "connector.login", Metric: "errorRate", Max: bound(0.01)}, 0.02, false, false},
+		"expect_failures":   {&Threshold{Route: "connector.login", Metric: "expectFailures", Max: bound(0)}, 3, false, false},
+		"min_failed":        {&Threshold{Route: "room.start", Kind: SummaryKindPush, Metric: "throughput", Min: bound(2)}, 1, false, false},
+		"between":           {&Threshold{Route: "room.start", Kind: SummaryKindPush, Metric: "count", Min: bound(5), Max: bound(10)}, 10, false, true},
+		"wrong_kind":        {&Threshold{Route: "room.start", Metric: "count", Min: bound(0)}, 0, true, false},
+	}
+
+	for name, table := range checkTable {
+		t.Run(name, func(t *testing.T) {
+			result := table.threshold.Check(summary)
+			assert.Equal(t, table.threshold, result.Threshold)
+			assert.Equal(t, table.value, result.Value)
+			assert.Equal(t, table.noData, result.NoData)
+			assert.Equal(t, table.passed, result.Passed)
+		})
+	}
+}
+
+func TestThresholdString(t *testing.T) {
+	threshold := &Threshold{Route: "connector.login", Metric: "errorRate", Min: bound(0), Max: bound(0.01)}
+	assert.Equal(t, "request connector.login errorRate >= 0 <= 0.01", threshold.String())
+}
+
+func TestShouldAbort(t *testing.T) {
+	summary := &Summary{Routes: []*RouteStats{
+		{Kind: SummaryKindRequest, Route: "connector.login", Count: 10, P95: 500},
+	}}
+	slow := &Threshold{Route: "connector.login", Metric: "p95", Max: bound(200), AbortOnFail: true, AbortAfter: "1m"}
+	missing := &Threshold{Route: "room.join", Metric: "count", Min: bound(1), AbortOnFail: true}
+	noAbort := &Threshold{Route: "connector.login", Metric: "count", Max: bound(5)}
+
+	_, ok := ShouldAbort([]*Threshold{slow, missing, noAbort}, summary, time.Second)
+	assert.False(t, ok)
+
+	result, ok := ShouldAbort([]*Threshold{slow, missing, noAbort}, summary, time.Minute)
+	assert.True(t, ok)
+	assert.Equal(t, slow, result.Threshold)
+	assert.Equal(t, 500.0, result.Value)
+}
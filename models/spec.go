@@ -1,6 +1,8 @@
 package models
 
 import (
+	"time"
+
 	"github.com/topfreegames/pitaya-bot/constants"
 )
 
@@ -11,6 +13,7 @@ type Spec struct {
 	PreRun               *InitialDefinitions `json:"preRun,omitempty"`
 	SequentialOperations []*Operation        `json:"sequentialOperations,omitempty"`
 	PostRun              *FinalDefinitions   `json:"postRun,omitempty"`
+	Executor             *Executor           `json:"executor,omitempty"`
 }
 
 // NewSpec returns a new spec
@@ -22,6 +25,108 @@ func NewSpec(name string) *Spec {
 	}
 }
 
+// GetExecutor returns the executor of the spec, defaulting to batch
+func (s *Spec) GetExecutor() *Executor {
+	if s.Executor == nil {
+		return &Executor{Type: ExecutorBatch}
+	}
+	return s.Executor
+}
+
+// Executors that can schedule the bots of a spec
+const (
+	// ExecutorBatch starts numberOfInstances bots and waits for all of them
+	// to finish before starting the next batch. This is the default executor
+	ExecutorBatch = "batch"
+	// ExecutorConstantArrivalRate starts bots at a fixed rate, regardless of
+	// how long the bots already running take
+	ExecutorConstantArrivalRate = "constantArrivalRate"
+	// ExecutorConstantBots runs numberOfInstances bots, each one running the
+	// spec repeatedly until the test duration expires
+	ExecutorConstantBots = "constantBots"
+	// ExecutorRampingBots keeps a number of bots running that follows the
+	// targets of its stages, each bot running the spec repeatedly
+	ExecutorRampingBots = "rampingBots"
+)
+
+// Executor defines how the launcher schedules the bots of a spec
+type Executor struct {
+	Type string `json:"type"`
+
+	// Constant arrival rate fields
+	// Rate is the number of bots started per second
+	Rate float64 `json:"rate,omitempty"`
+	// MaxConcurrency is the maximum number of bots running at the same time
+	MaxConcurrency int `json:"maxConcurrency,omitempty"`
+
+	// Constant bots fields
+	// Iterations is the maximum number of times each bot runs the spec,
+	// unlimited when zero
+	Iterations int `json:"iterations,omitempty"`
+
+	// Ramping bots fields
+	Stages []*Stage `json:"stages,omitempty"`
+}
+
+// Stage moves the number of running bots linearly from the target of the
+// previous stage, or zero, to its target during its duration
+type Stage struct {
+	Duration string `json:"duration"`
+	Target   int    `json:"target"`
+}
+
+// GetDuration returns the parsed stage duration
+func (s *Stage) GetDuration() (time.Duration, error) {
+	d, err := time.ParseDuration(s.Duration)
+	if err != nil || d < 0 {
+		return 0, constants.ErrSpecInvalidStages
+	}
+	return d, nil
+}
+
+// MaxArrivalRate is the highest rate of the constant arrival rate executor,
+// starting a bot every microsecond
+const MaxArrivalRate = 1e6
+
+// Validate returns an error if the executor is unknown or misses parameters
+func (e *Executor) Validate() error {
+	switch e.Type {
+	case ExecutorBatch:
+		return nil
+	case ExecutorConstantArrivalRate:
+		if e.Rate <= 0 || e.Rate > MaxArrivalRate || e.MaxConcurrency <= 0 {
+			return constants.ErrSpecInvalidExecutor
+		}
+		return nil
+	case ExecutorConstantBots:
+		if e.Iterations < 0 {
+			return constants.ErrSpecInvalidExecutor
+		}
+		return nil
+	case ExecutorRampingBots:
+		return e.validateStages()
+	}
+
+	return constants.ErrSpecInvalidExecutor
+}
+
+func (e *Executor) validateStages() error {
+	if len(e.Stages) == 0 {
+		return constants.ErrSpecInvalidStages
+	}
+
+	for _, stage := range e.Stages {
+		if stage == nil || stage.Target < 0 {
+			return constants.ErrSpecInvalidStages
+		}
+		if _, err := stage.GetDuration(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 // InitialDefinitions are set before running each bot
 type InitialDefinitions struct {
 	Function string                 `json:"function,omitempty"`
@@ -43,10 +148,62 @@ type StoreSpecEntry struct {
 // StoreSpec ...
 type StoreSpec map[string]StoreSpecEntry
 
+// Operators that can be used to compare a value received from the server
+// with the expected one
+const (
+	OperatorEqual          = "eq"
+	OperatorNotEqual       = "ne"
+	OperatorGreater        = "gt"
+	OperatorGreaterOrEqual = "gte"
+	OperatorLess           = "lt"
+	OperatorLessOrEqual    = "lte"
+	OperatorRegex          = "regex"
+	OperatorContains       = "contains"
+	OperatorIn             = "in"
+	OperatorExists         = "exists"
+	OperatorNotExists      = "notExists"
+	OperatorLen            = "len"
+)
+
+var validOperators = map[string]bool{
+	OperatorEqual:          true,
+	OperatorNotEqual:       true,
+	OperatorGreater:        true,
+	OperatorGreaterOrEqual: true,
+	OperatorLess:           true,
+	OperatorLessOrEqual:    true,
+	OperatorRegex:          true,
+	OperatorContains:       true,
+	OperatorIn:             true,
+	OperatorExists:         true,
+	OperatorNotExists:      true,
+	OperatorLen:            true,
+}
+
 // ExpectSpecEntry ...
 type ExpectSpecEntry struct {
-	Type  string      `json:"type"`
-	Value interface{} `json:"value"`
+	Type              string      `json:"type"`
+	Value             interface{} `json:"value"`
+	Operator          string      `json:"operator,omitempty"`
+	IgnoreOrder       bool        `json:"ignoreOrder,omitempty"`
+	IgnoreExtraFields bool        `json:"ignoreExtraFields,omitempty"`
+}
+
+// GetOperator returns the entry operator, defaulting to equality
+func (e ExpectSpecEntry) GetOperator() string {
+	if e.Operator == "" {
+		return OperatorEqual
+	}
+	return e.Operator
+}
+
+// Validate returns an error if the entry uses an unknown operator
+func (e ExpectSpecEntry) Validate() error {
+	if !validOperators[e.GetOperator()] {
+		return constants.ErrSpecInvalidOperator
+	}
+
+	return nil
 }
 
 // ExpectSpec  ...
@@ -61,11 +218,166 @@ type Operation struct {
 	Expect  ExpectSpec             `json:"expect,omitempty"`
 	Store   StoreSpec              `json:"store,omitempty"`
 	Change  map[string]interface{} `json:"change,omitempty"`
+
+	// ThinkTime is the pause after the operation succeeds
+	ThinkTime *Delay `json:"thinkTime,omitempty"`
+
+	// Retry is the policy applied when a request fails
+	Retry *RetryPolicy `json:"retry,omitempty"`
+
+	// OnFailure is what the bot does when the operation fails
+	OnFailure string `json:"onFailure,omitempty"`
+	// SoftAssert records failed expectations without failing the operation
+	SoftAssert bool `json:"softAssert,omitempty"`
+
+	// Sleep fields
+	Duration *Delay `json:"duration,omitempty"`
+
+	// Nested operations, run by loop and by if when its condition holds
+	Operations []*Operation `json:"operations,omitempty"`
+
+	// Loop fields
+	Count         int        `json:"count,omitempty"`
+	While         ExpectSpec `json:"while,omitempty"`
+	MaxIterations int        `json:"maxIterations,omitempty"`
+
+	// If fields
+	Condition ExpectSpec   `json:"condition,omitempty"`
+	Else      []*Operation `json:"else,omitempty"`
+
+	// Choice fields
+	Choices []*Choice `json:"choices,omitempty"`
+
+	// Listen and noPush fields, used instead of URI to watch several routes
+	Routes  []*PushRoute `json:"routes,omitempty"`
+	Ordered bool         `json:"ordered,omitempty"`
 }
 
-// Validate returns an error if the operation is malformed
-// TODO -- more validations
+// PushRoute is a route a listen operation waits for, with the expectations
+// and the data to store when the push arrives on it
+type PushRoute struct {
+	URI    string     `json:"uri"`
+	Expect ExpectSpec `json:"expect,omitempty"`
+	Store  StoreSpec  `json:"store,omitempty"`
+}
+
+// Policies applied when an operation fails
+const (
+	// OnFailureAbort stops the bot, it is the default policy
+	OnFailureAbort = "abort"
+	// OnFailureContinue records the failure and runs the next operation
+	OnFailureContinue = "continue"
+	// OnFailureRestart runs the spec operations again from the first one
+	OnFailureRestart = "restart"
+)
+
+// GetOnFailure returns the failure policy of the operation, defaulting to abort
+func (o *Operation) GetOnFailure() string {
+	if o.OnFailure == "" {
+		return OnFailureAbort
+	}
+	return o.OnFailure
+}
+
+// Distributions that can be used to draw a delay
+const (
+	DistributionConstant    = "constant"
+	DistributionUniform     = "uniform"
+	DistributionNormal      = "normal"
+	DistributionExponential = "exponential"
+)
+
+// Delay describes a pause drawn from a distribution, all values are in milliseconds
+type Delay struct {
+	Distribution string  `json:"distribution,omitempty"`
+	Value        float64 `json:"value,omitempty"`
+	Min          float64 `json:"min,omitempty"`
+	Max          float64 `json:"max,omitempty"`
+	Mean         float64 `json:"mean,omitempty"`
+	StdDev       float64 `json:"stdDev,omitempty"`
+}
+
+// GetDistribution returns the delay distribution, defaulting to constant
+func (d *Delay) GetDistribution() string {
+	if d.Distribution == "" {
+		return DistributionConstant
+	}
+	return d.Distribution
+}
+
+// Validate returns an error if the delay parameters don't fit its distribution
+func (d *Delay) Validate() error {
+	if d == nil || d.Min < 0 || d.Max < 0 {
+		return constants.ErrSpecInvalidDelay
+	}
+
+	valid := false
+	switch d.GetDistribution() {
+	case DistributionConstant:
+		valid = d.Value >= 0
+	case DistributionUniform:
+		valid = d.Min <= d.Max && d.Max > 0
+	case DistributionNormal:
+		valid = d.Mean >= 0 && d.StdDev >= 0
+	case DistributionExponential:
+		valid = d.Mean > 0
+	}
+
+	if !valid {
+		return constants.ErrSpecInvalidDelay
+	}
+
+	return nil
+}
+
+// RetryPolicy defines which request failures are retried and how many times
+type RetryPolicy struct {
+	// MaxAttempts is the number of attempts, including the first one
+	MaxAttempts     int    `json:"maxAttempts"`
+	Backoff         *Delay `json:"backoff,omitempty"`
+	OnTimeout       bool   `json:"onTimeout,omitempty"`
+	OnExpectFailure bool   `json:"onExpectFailure,omitempty"`
+}
+
+// Validate returns an error if the policy would never retry
+func (r *RetryPolicy) Validate() error {
+	if r.MaxAttempts < 1 || (!r.OnTimeout && !r.OnExpectFailure) {
+		return constants.ErrSpecInvalidRetry
+	}
+
+	if r.Backoff != nil {
+		return r.Backoff.Validate()
+	}
+
+	return nil
+}
+
+// Choice is a weighted sequence of operations of a choice operation
+type Choice struct {
+	Name       string       `json:"name,omitempty"`
+	Weight     float64      `json:"weight"`
+	Operations []*Operation `json:"operations"`
+}
+
+// Validate returns an error if the operation, or any nested operation, is malformed
 func (o *Operation) Validate() error {
+	if err := o.ValidateFields(); err != nil {
+		return err
+	}
+
+	for _, op := range o.Children() {
+		if err := op.Validate(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ValidateFields returns an error if the operation is malformed, without
+// validating its nested operations
+// TODO -- more validations
+func (o *Operation) ValidateFields() error {
 	if o == nil {
 		return constants.ErrSpecInvalidNil
 	}
@@ -75,10 +387,182 @@ func (o *Operation) Validate() error {
 		return constants.ErrSpecInvalidType
 	}
 
+	if o.ThinkTime != nil {
+		if err := o.ThinkTime.Validate(); err != nil {
+			return err
+		}
+	}
+
+	switch o.GetOnFailure() {
+	case OnFailureAbort, OnFailureContinue, OnFailureRestart:
+	default:
+		return constants.ErrSpecInvalidOnFailure
+	}
+
+	if o.Retry != nil {
+		if o.Type != "request" {
+			// only requests can be retried
+			return constants.ErrSpecInvalidRetry
+		}
+		if err := o.Retry.Validate(); err != nil {
+			return err
+		}
+	}
+
+	switch o.Type {
+	case "loop":
+		return o.validateLoop()
+	case "if":
+		return o.validateIf()
+	case "choice":
+		return o.validateChoice()
+	case "sleep":
+		return o.Duration.Validate()
+	case "listen":
+		if o.Timeout <= 0 {
+			// must stop waiting for the push at some point
+			return constants.ErrSpecInvalidTimeout
+		}
+		if len(o.Routes) > 0 || o.Ordered {
+			return o.validateRoutes()
+		}
+	case "noPush":
+		if o.Timeout <= 0 {
+			// must watch the routes for a while
+			return constants.ErrSpecInvalidTimeout
+		}
+		if len(o.Routes) > 0 || o.Ordered {
+			return o.validateRoutes()
+		}
+	}
+
 	if o.URI == "" {
 		// must have a URI specified
 		return constants.ErrSpecInvalidURI
 	}
 
+	return validateExpectSpec(o.Expect)
+}
+
+// ListenRoutes returns the routes a listen operation waits for
+func (o *Operation) ListenRoutes() []string {
+	if len(o.Routes) == 0 {
+		return []string{o.URI}
+	}
+
+	routes := make([]string, len(o.Routes))
+	for idx, route := range o.Routes {
+		routes[idx] = route.URI
+	}
+	return routes
+}
+
+// GetRoute returns the route of a listen operation with the given URI
+func (o *Operation) GetRoute(uri string) *PushRoute {
+	for _, route := range o.Routes {
+		if route.URI == uri {
+			return route
+		}
+	}
+	return nil
+}
+
+// Children returns the operations nested inside the operation
+func (o *Operation) Children() []*Operation {
+	children := make([]*Operation, 0, len(o.Operations)+len(o.Else))
+	children = append(children, o.Operations...)
+	children = append(children, o.Else...)
+	for _, choice := range o.Choices {
+		if choice != nil {
+			children = append(children, choice.Operations...)
+		}
+	}
+
+	return children
+}
+
+func (o *Operation) validateLoop() error {
+	if o.Count <= 0 && len(o.While) == 0 {
+		// must be bounded by a count or a condition
+		return constants.ErrSpecInvalidLoop
+	}
+
+	if len(o.Operations) == 0 {
+		return constants.ErrSpecInvalidOperations
+	}
+
+	return validateExpectSpec(o.While)
+}
+
+func (o *Operation) validateIf() error {
+	if len(o.Condition) == 0 {
+		return constants.ErrSpecInvalidCondition
+	}
+
+	if len(o.Operations) == 0 && len(o.Else) == 0 {
+		return constants.ErrSpecInvalidOperations
+	}
+
+	return validateExpectSpec(o.Condition)
+}
+
+func (o *Operation) validateChoice() error {
+	if o.URI == "" {
+		// the URI names the choice in its metrics
+		return constants.ErrSpecInvalidURI
+	}
+
+	if len(o.Choices) == 0 {
+		return constants.ErrSpecInvalidChoices
+	}
+
+	total := 0.0
+	for _, choice := range o.Choices {
+		if choice == nil || choice.Weight < 0 || len(choice.Operations) == 0 {
+			return constants.ErrSpecInvalidChoices
+		}
+		total += choice.Weight
+	}
+
+	if total <= 0 {
+		// at least one choice must be selectable
+		return constants.ErrSpecInvalidChoices
+	}
+
+	return nil
+}
+
+func (o *Operation) validateRoutes() error {
+	if len(o.Routes) == 0 || (o.Ordered && o.Type != "listen") {
+		// ordered listens need the sequence of routes
+		return constants.ErrSpecInvalidRoutes
+	}
+
+	seen := make(map[string]bool, len(o.Routes))
+	for _, route := range o.Routes {
+		if route == nil || route.URI == "" {
+			return constants.ErrSpecInvalidRoutes
+		}
+		if seen[route.URI] && !o.Ordered {
+			// an unordered listen can't tell which block a push belongs to
+			return constants.ErrSpecInvalidRoutes
+		}
+		seen[route.URI] = true
+
+		if err := validateExpectSpec(route.Expect); err != nil {
+			return err
+		}
+	}
+
+	return validateExpectSpec(o.Expect)
+}
+
+func validateExpectSpec(expect ExpectSpec) error {
+	for _, entry := range expect {
+		if err := entry.Validate(); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
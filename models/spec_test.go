@@ -10,13 +10,106 @@ import (
 func TestOperationValidate(t *testing.T) {
 	var tables = map[string]struct {
 		op  *Operation
-		err    error
+		err error
 	}{
-		"success_default": {&Operation{Type: "listen", URI: "metagame.someHandler.someRoute"}, nil},
+		"success_default": {&Operation{Type: "listen", Timeout: 1000, URI: "metagame.someHandler.someRoute"}, nil},
 
 		"err_nil":     {nil, constants.ErrSpecInvalidNil},
 		"err_no_type": {&Operation{Type: ""}, constants.ErrSpecInvalidType},
-		"err_no_uri":  {&Operation{Type: "listen"}, constants.ErrSpecInvalidURI},
+		"err_no_uri":  {&Operation{Type: "listen", Timeout: 1000}, constants.ErrSpecInvalidURI},
+
+		"err_listen_no_timeout": {&Operation{Type: "listen", URI: "metagame.someHandler.someRoute"}, constants.ErrSpecInvalidTimeout},
+		"success_operator": {&Operation{Type: "request", URI: "connector.handler.route", Expect: ExpectSpec{
+			"$response.gold": ExpectSpecEntry{Type: "int", Value: 100, Operator: OperatorGreaterOrEqual},
+		}}, nil},
+		"err_unknown_operator": {&Operation{Type: "request", URI: "connector.handler.route", Expect: ExpectSpec{
+			"$response.gold": ExpectSpecEntry{Type: "int", Value: 100, Operator: "between"},
+		}}, constants.ErrSpecInvalidOperator},
+		"success_loop_count": {&Operation{Type: "loop", Count: 10, Operations: []*Operation{
+			{Type: "request", URI: "connector.shopHandler.buy"},
+		}}, nil},
+		"success_loop_while": {&Operation{Type: "loop", While: ExpectSpec{
+			"$response.status": ExpectSpecEntry{Type: "string", Value: "searching"},
+		}, Operations: []*Operation{
+			{Type: "request", URI: "connector.matchHandler.status"},
+		}}, nil},
+		"err_loop_unbounded": {&Operation{Type: "loop", Operations: []*Operation{
+			{Type: "request", URI: "connector.shopHandler.buy"},
+		}}, constants.ErrSpecInvalidLoop},
+		"err_loop_no_operations": {&Operation{Type: "loop", Count: 1}, constants.ErrSpecInvalidOperations},
+		"err_loop_nested":        {&Operation{Type: "loop", Count: 1, Operations: []*Operation{{Type: "request"}}}, constants.ErrSpecInvalidURI},
+		"success_if": {&Operation{Type: "if", Condition: ExpectSpec{
+			"$response.code": ExpectSpecEntry{Type: "string", Value: "404"},
+		}, Operations: []*Operation{
+			{Type: "request", URI: "connector.playerHandler.create"},
+		}, Else: []*Operation{
+			{Type: "request", URI: "connector.playerHandler.authenticate"},
+		}}, nil},
+		"err_if_no_condition": {&Operation{Type: "if", Operations: []*Operation{
+			{Type: "request", URI: "connector.playerHandler.create"},
+		}}, constants.ErrSpecInvalidCondition},
+		"err_if_no_operations": {&Operation{Type: "if", Condition: ExpectSpec{
+			"$response.code": ExpectSpecEntry{Type: "string", Value: "404"},
+		}}, constants.ErrSpecInvalidOperations},
+		"err_if_nested_else": {&Operation{Type: "if", Condition: ExpectSpec{
+			"$response.code": ExpectSpecEntry{Type: "string", Value: "404"},
+		}, Else: []*Operation{{Type: ""}}}, constants.ErrSpecInvalidType},
+		"success_choice": {&Operation{Type: "choice", URI: "player-mix", Choices: []*Choice{
+			{Name: "shop", Weight: 60, Operations: []*Operation{{Type: "request", URI: "connector.shopHandler.browse"}}},
+			{Name: "chat", Weight: 0, Operations: []*Operation{{Type: "notify", URI: "connector.chatHandler.send"}}},
+		}}, nil},
+		"err_choice_no_uri": {&Operation{Type: "choice", Choices: []*Choice{
+			{Weight: 1, Operations: []*Operation{{Type: "request", URI: "connector.shopHandler.browse"}}},
+		}}, constants.ErrSpecInvalidURI},
+		"err_choice_empty": {&Operation{Type: "choice", URI: "player-mix"}, constants.ErrSpecInvalidChoices},
+		"err_choice_negative_weight": {&Operation{Type: "choice", URI: "player-mix", Choices: []*Choice{
+			{Weight: -1, Operations: []*Operation{{Type: "request", URI: "connector.shopHandler.browse"}}},
+		}}, constants.ErrSpecInvalidChoices},
+		"err_choice_zero_weights": {&Operation{Type: "choice", URI: "player-mix", Choices: []*Choice{
+			{Weight: 0, Operations: []*Operation{{Type: "request", URI: "connector.shopHandler.browse"}}},
+		}}, constants.ErrSpecInvalidChoices},
+		"err_choice_no_operations":  {&Operation{Type: "choice", URI: "player-mix", Choices: []*Choice{{Weight: 1}}}, constants.ErrSpecInvalidChoices},
+		"success_sleep_constant":    {&Operation{Type: "sleep", Duration: &Delay{Value: 100}}, nil},
+		"success_sleep_uniform":     {&Operation{Type: "sleep", Duration: &Delay{Distribution: "uniform", Min: 100, Max: 500}}, nil},
+		"success_sleep_normal":      {&Operation{Type: "sleep", Duration: &Delay{Distribution: "normal", Mean: 300, StdDev: 50}}, nil},
+		"success_sleep_exponential": {&Operation{Type: "sleep", Duration: &Delay{Distribution: "exponential", Mean: 300, Max: 2000}}, nil},
+		"err_sleep_no_duration":     {&Operation{Type: "sleep"}, constants.ErrSpecInvalidDelay},
+		"err_sleep_uniform":         {&Operation{Type: "sleep", Duration: &Delay{Distribution: "uniform", Min: 500, Max: 100}}, constants.ErrSpecInvalidDelay},
+		"err_sleep_exponential":     {&Operation{Type: "sleep", Duration: &Delay{Distribution: "exponential"}}, constants.ErrSpecInvalidDelay},
+		"err_sleep_unknown":         {&Operation{Type: "sleep", Duration: &Delay{Distribution: "poisson", Mean: 1}}, constants.ErrSpecInvalidDelay},
+		"success_think_time":        {&Operation{Type: "request", URI: "connector.shopHandler.buy", ThinkTime: &Delay{Distribution: "uniform", Max: 1000}}, nil},
+		"err_think_time":            {&Operation{Type: "request", URI: "connector.shopHandler.buy", ThinkTime: &Delay{Value: -1}}, constants.ErrSpecInvalidDelay},
+		"success_listen_routes": {&Operation{Type: "listen", Timeout: 1000, Routes: []*PushRoute{
+			{URI: "connector.matchHandler.found", Store: StoreSpec{"roomId": {Type: "string", Value: "$response.room.id"}}},
+			{URI: "connector.matchHandler.cancelled"},
+		}}, nil},
+		"success_listen_ordered": {&Operation{Type: "listen", Timeout: 1000, Ordered: true, Routes: []*PushRoute{
+			{URI: "connector.roomHandler.update"},
+			{URI: "connector.roomHandler.update"},
+		}}, nil},
+		"err_listen_ordered_no_routes": {&Operation{Type: "listen", Timeout: 1000, URI: "connector.roomHandler.update", Ordered: true}, constants.ErrSpecInvalidRoutes},
+		"err_listen_route_no_uri":      {&Operation{Type: "listen", Timeout: 1000, Routes: []*PushRoute{{}}}, constants.ErrSpecInvalidRoutes},
+		"err_listen_duplicated_route": {&Operation{Type: "listen", Timeout: 1000, Routes: []*PushRoute{
+			{URI: "connector.roomHandler.update"},
+			{URI: "connector.roomHandler.update"},
+		}}, constants.ErrSpecInvalidRoutes},
+		"success_no_push":        {&Operation{Type: "noPush", URI: "connector.playerHandler.kick", Timeout: 500}, nil},
+		"err_no_push_no_timeout": {&Operation{Type: "noPush", URI: "connector.playerHandler.kick"}, constants.ErrSpecInvalidTimeout},
+		"err_no_push_ordered":    {&Operation{Type: "noPush", Timeout: 500, Ordered: true, Routes: []*PushRoute{{URI: "connector.playerHandler.kick"}}}, constants.ErrSpecInvalidRoutes},
+		"success_retry": {&Operation{Type: "request", URI: "connector.shopHandler.buy", Timeout: 500, Retry: &RetryPolicy{
+			MaxAttempts: 3, Backoff: &Delay{Value: 100}, OnTimeout: true,
+		}}, nil},
+		"err_retry_no_attempts":  {&Operation{Type: "request", URI: "connector.shopHandler.buy", Retry: &RetryPolicy{OnTimeout: true}}, constants.ErrSpecInvalidRetry},
+		"err_retry_no_condition": {&Operation{Type: "request", URI: "connector.shopHandler.buy", Retry: &RetryPolicy{MaxAttempts: 3}}, constants.ErrSpecInvalidRetry},
+		"err_retry_backoff": {&Operation{Type: "request", URI: "connector.shopHandler.buy", Retry: &RetryPolicy{
+			MaxAttempts: 3, Backoff: &Delay{Value: -1}, OnTimeout: true,
+		}}, constants.ErrSpecInvalidDelay},
+		"err_retry_notify":       {&Operation{Type: "notify", URI: "connector.chatHandler.send", Retry: &RetryPolicy{MaxAttempts: 3, OnTimeout: true}}, constants.ErrSpecInvalidRetry},
+		"success_on_failure":     {&Operation{Type: "request", URI: "connector.shopHandler.buy", OnFailure: OnFailureContinue, SoftAssert: true}, nil},
+		"err_on_failure_unknown": {&Operation{Type: "request", URI: "connector.shopHandler.buy", OnFailure: "ignore"}, constants.ErrSpecInvalidOnFailure},
+		"err_listen_route_operator": {&Operation{Type: "listen", Timeout: 1000, Routes: []*PushRoute{
+			{URI: "connector.matchHandler.found", Expect: ExpectSpec{"$response.code": {Operator: "between"}}},
+		}}, constants.ErrSpecInvalidOperator},
 	}
 
 	for name, table := range tables {
@@ -27,3 +120,30 @@ func TestOperationValidate(t *testing.T) {
 	}
 }
 
+func TestExecutorValidate(t *testing.T) {
+	tables := map[string]struct {
+		executor *Executor
+		err      error
+	}{
+		"success_default":       {NewSpec("test").GetExecutor(), nil},
+		"success_arrival_rate":  {&Executor{Type: ExecutorConstantArrivalRate, Rate: 10, MaxConcurrency: 100}, nil},
+		"err_arrival_rate_rate": {&Executor{Type: ExecutorConstantArrivalRate, MaxConcurrency: 100}, constants.ErrSpecInvalidExecutor},
+		"err_arrival_rate_max":  {&Executor{Type: ExecutorConstantArrivalRate, Rate: 2e9, MaxConcurrency: 100}, constants.ErrSpecInvalidExecutor},
+		"err_arrival_rate_cap":  {&Executor{Type: ExecutorConstantArrivalRate, Rate: 10}, constants.ErrSpecInvalidExecutor},
+		"err_unknown":           {&Executor{Type: "closedLoop"}, constants.ErrSpecInvalidExecutor},
+		"success_ramping": {&Executor{Type: ExecutorRampingBots, Stages: []*Stage{
+			{Duration: "5m", Target: 500}, {Duration: "20m", Target: 500}, {Duration: "2m"},
+		}}, nil},
+		"success_constant_bots":        {&Executor{Type: ExecutorConstantBots, Iterations: 10}, nil},
+		"err_constant_bots_iterations": {&Executor{Type: ExecutorConstantBots, Iterations: -1}, constants.ErrSpecInvalidExecutor},
+		"err_ramping_no_stages":        {&Executor{Type: ExecutorRampingBots}, constants.ErrSpecInvalidStages},
+		"err_ramping_duration":         {&Executor{Type: ExecutorRampingBots, Stages: []*Stage{{Duration: "5 minutes", Target: 500}}}, constants.ErrSpecInvalidStages},
+		"err_ramping_neg_target":       {&Executor{Type: ExecutorRampingBots, Stages: []*Stage{{Duration: "5m", Target: -1}}}, constants.ErrSpecInvalidStages},
+	}
+
+	for name, table := range tables {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, table.err, table.executor.Validate())
+		})
+	}
+}
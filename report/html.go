@@ -0,0 +1,129 @@
+package report
+
+import (
+	"html/template"
+	"io"
+
+	"github.com/topfreegames/pitaya-bot/metrics"
+)
+
+// HTMLWriter writes the report as a self-contained HTML page, with its
+// styles and latency charts inline
+type HTMLWriter struct{}
+
+// chartWidth is the width in pixels of the longest bar of a latency chart
+const chartWidth = 400.0
+
+// latencyBar is a bar of the latency chart of a route
+type latencyBar struct {
+	Label string
+	Value float64
+	Width float64
+	Y     int
+}
+
+// latencyBars returns the bars of the route latencies, scaled so its max
+// latency fills the chart
+func latencyBars(r *metrics.RouteStats) []*latencyBar {
+	bars := []*latencyBar{
+		{Label: "min", Value: r.Min},
+		{Label: "mean", Value: r.Mean},
+		{Label: "p50", Value: r.P50},
+		{Label: "p90", Value: r.P90},
+		{Label: "p95", Value: r.P95},
+		{Label: "p99", Value: r.P99},
+		{Label: "max", Value: r.Max},
+	}
+	for idx, bar := range bars {
+		bar.Y = idx * 20
+		if r.Max > 0 {
+			bar.Width = chartWidth * bar.Value / r.Max
+		}
+	}
+	return bars
+}
+
+var htmlTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"latencyBars": latencyBars,
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>pitaya-bot report</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+table { border-collapse: collapse; margin-bottom: 1.5em; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: left; }
+th { background: #f0f0f0; }
+.passed { color: #1a7f37; }
+.failed { color: #cf222e; }
+pre { background: #f6f8fa; padding: 8px; white-space: pre-wrap; }
+svg text { font-size: 12px; }
+</style>
+</head>
+<body>
+<h1>pitaya-bot report</h1>
+<p>
+Started at {{.Start.Format "2006-01-02 15:04:05 MST"}}, ran for {{printf "%.1f" .Duration}}s.
+{{if .Passed}}<span class="passed">Passed</span>{{else}}<span class="failed">Failed</span>{{end}}{{if .Interrupted}}, interrupted{{end}}.
+</p>
+
+{{if .Thresholds}}
+<h2>Thresholds</h2>
+<table>
+<tr><th>Threshold</th><th>Value</th><th>Result</th></tr>
+{{range .Thresholds}}
+<tr><td>{{.Threshold}}</td><td>{{if .NoData}}no data{{else}}{{printf "%g" .Value}}{{end}}</td>
+<td>{{if .Passed}}<span class="passed">passed</span>{{else}}<span class="failed">failed</span>{{end}}</td></tr>
+{{end}}
+</table>
+{{end}}
+
+<h2>Routes</h2>
+<table>
+<tr><th>Kind</th><th>Route</th><th>Count</th><th>Errors</th><th>Expect failures</th><th>Min</th><th>Mean</th><th>P50</th><th>P90</th><th>P95</th><th>P99</th><th>Max</th><th>Rate/s</th></tr>
+{{range .Routes}}
+<tr><td>{{.Kind}}</td><td>{{.Route}}</td><td>{{.Count}}</td><td>{{.Errors}}</td><td>{{.ExpectFailures}}</td>
+<td>{{printf "%.2f" .Min}}</td><td>{{printf "%.2f" .Mean}}</td><td>{{printf "%.2f" .P50}}</td><td>{{printf "%.2f" .P90}}</td>
+<td>{{printf "%.2f" .P95}}</td><td>{{printf "%.2f" .P99}}</td><td>{{printf "%.2f" .Max}}</td><td>{{printf "%.2f" .Throughput}}</td></tr>
+{{end}}
+</table>
+
+{{range .Routes}}{{if .Count}}
+<h3>{{.Kind}} {{.Route}} latency (ms)</h3>
+<svg width="560" height="140">
+{{range latencyBars .}}
+<text x="0" y="{{.Y}}" dy="14">{{.Label}}</text>
+<rect x="50" y="{{.Y}}" width="{{printf "%.1f" .Width}}" height="16" fill="#4c78a8"></rect>
+<text x="{{printf "%.1f" .Width}}" y="{{.Y}}" dx="56" dy="14">{{printf "%.2f" .Value}}</text>
+{{end}}
+</svg>
+{{end}}{{end}}
+
+<h2>Specs</h2>
+{{range .Specs}}
+<h3>{{.Name}}</h3>
+<table>
+<tr><th>Operation</th><th>Result</th></tr>
+{{range .Operations}}
+<tr><td>{{.Name}}</td><td>{{if .Failed}}<span class="failed">failed</span>{{else}}<span class="passed">passed</span>{{end}}</td></tr>
+{{end}}
+</table>
+{{range .Operations}}{{$op := .}}{{range .Failures}}
+<p class="failed">{{$op.Name}}: {{.Count}} bots failed with {{.Kind}}</p>
+<pre>{{.Body}}</pre>
+{{end}}{{end}}
+{{range .Failures}}
+<p class="failed">{{.Count}} bots failed outside of operations with {{.Kind}}</p>
+<pre>{{.Body}}</pre>
+{{end}}
+{{end}}
+</body>
+</html>
+`))
+
+// Write writes the report as HTML
+//  - implements the Write method of the Writer interface
+func (h *HTMLWriter) Write(r *Report, w io.Writer) error {
+	return htmlTemplate.Execute(w, r)
+}
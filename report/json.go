@@ -0,0 +1,17 @@
+package report
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// JSONWriter writes the report as indented JSON
+type JSONWriter struct{}
+
+// Write writes the report as JSON
+//  - implements the Write method of the Writer interface
+func (j *JSONWriter) Write(r *Report, w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r)
+}
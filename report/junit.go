@@ -0,0 +1,123 @@
+package report
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+)
+
+// JUnitWriter writes the report as JUnit XML. Each spec is a test suite
+// whose test cases are its top level operations, thresholds have a suite of
+// their own
+type JUnitWriter struct{}
+
+type junitTestSuites struct {
+	XMLName  xml.Name          `xml:"testsuites"`
+	Name     string            `xml:"name,attr"`
+	Tests    int               `xml:"tests,attr"`
+	Failures int               `xml:"failures,attr"`
+	Time     float64           `xml:"time,attr"`
+	Suites   []*junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string           `xml:"name,attr"`
+	Tests     int              `xml:"tests,attr"`
+	Failures  int              `xml:"failures,attr"`
+	Time      float64          `xml:"time,attr"`
+	TestCases []*junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Body    string `xml:",chardata"`
+}
+
+// Write writes the report as JUnit XML
+//  - implements the Write method of the Writer interface
+func (j *JUnitWriter) Write(r *Report, w io.Writer) error {
+	suites := &junitTestSuites{Name: "pitaya-bot", Time: r.Duration}
+	for _, s := range r.Specs {
+		suite := &junitTestSuite{Name: s.Name, Time: s.Duration}
+		for _, op := range s.Operations {
+			suite.add(&junitTestCase{Name: op.Name(), ClassName: s.Name, Failure: newJUnitFailure(op.Failures)})
+		}
+		if len(s.Failures) > 0 {
+			// bots may fail before running any operation
+			suite.add(&junitTestCase{Name: "bot", ClassName: s.Name, Failure: newJUnitFailure(s.Failures)})
+		}
+		suites.add(suite)
+	}
+
+	if len(r.Thresholds) > 0 {
+		suite := &junitTestSuite{Name: "thresholds", Time: r.Duration}
+		for _, t := range r.Thresholds {
+			tc := &junitTestCase{Name: t.Threshold, ClassName: "thresholds"}
+			if !t.Passed {
+				message := fmt.Sprintf("threshold crossed with %g", t.Value)
+				if t.NoData {
+					message = "route was never reported"
+				}
+				tc.Failure = &junitFailure{Message: message, Type: "Threshold"}
+			}
+			suite.add(tc)
+		}
+		suites.add(suite)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(suites); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func (s *junitTestSuite) add(tc *junitTestCase) {
+	s.TestCases = append(s.TestCases, tc)
+	s.Tests++
+	if tc.Failure != nil {
+		s.Failures++
+	}
+}
+
+func (s *junitTestSuites) add(suite *junitTestSuite) {
+	s.Suites = append(s.Suites, suite)
+	s.Tests += suite.Tests
+	s.Failures += suite.Failures
+}
+
+// newJUnitFailure describes the failures of the bots in a single JUnit
+// failure, taking the body from the first one. It returns nil if no bot failed
+func newJUnitFailure(failures []*Failure) *junitFailure {
+	if len(failures) == 0 {
+		return nil
+	}
+
+	count := 0
+	for _, f := range failures {
+		count += f.Count
+	}
+	first := failures[0]
+	body := first.Body
+	for _, f := range failures[1:] {
+		body += fmt.Sprintf("\n%d bots also failed with: %s", f.Count, f.Message)
+	}
+
+	return &junitFailure{
+		Message: fmt.Sprintf("%d bots failed: %s", count, first.Message),
+		Type:    first.Kind,
+		Body:    body,
+	}
+}
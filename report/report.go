@@ -0,0 +1,258 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/topfreegames/pitaya-bot/bot"
+	"github.com/topfreegames/pitaya-bot/metrics"
+	"github.com/topfreegames/pitaya-bot/models"
+)
+
+// Report is the outcome of a run, gathered at the end of launcher.Launch
+type Report struct {
+	mutex sync.Mutex
+
+	Start       time.Time             `json:"start"`
+	Duration    float64               `json:"duration"`
+	Interrupted bool                  `json:"interrupted"`
+	Specs       []*SpecReport         `json:"specs"`
+	Routes      []*metrics.RouteStats `json:"routes"`
+	Thresholds  []*ThresholdReport    `json:"thresholds"`
+	Errors      map[string]int        `json:"errors"`
+}
+
+// SpecReport is the outcome of the bots that ran a spec
+type SpecReport struct {
+	Name       string             `json:"name"`
+	Duration   float64            `json:"duration"`
+	Operations []*OperationReport `json:"operations"`
+	// Failures are the bots that failed outside of its operations, such as
+	// when connecting
+	Failures []*Failure     `json:"failures"`
+	Errors   map[string]int `json:"errors"`
+}
+
+// OperationReport is the outcome of a top level operation of a spec
+type OperationReport struct {
+	Step     int        `json:"step"`
+	Type     string     `json:"type"`
+	URI      string     `json:"uri"`
+	Failures []*Failure `json:"failures"`
+}
+
+// Failure groups the bots that failed the same way, such as failing the
+// same expectation of an operation, whatever data they received
+type Failure struct {
+	// Kind is the type of the error, such as ExpectError
+	Kind string `json:"kind"`
+	// Group identifies the failure, such as "$response.code eq failed"
+	Group string `json:"group"`
+	// Message and Body hold the details of the first bot that failed, such
+	// as the response that didn't match the expectations
+	Message string `json:"message"`
+	Body    string `json:"body"`
+	Count   int    `json:"count"`
+}
+
+// ThresholdReport is the outcome of a threshold
+type ThresholdReport struct {
+	Threshold string  `json:"threshold"`
+	Value     float64 `json:"value"`
+	NoData    bool    `json:"noData"`
+	Passed    bool    `json:"passed"`
+}
+
+// NewReport returns a new report of a run started at start
+func NewReport(start time.Time) *Report {
+	return &Report{
+		Start:  start,
+		Specs:  []*SpecReport{},
+		Errors: map[string]int{},
+	}
+}
+
+// AddSpec adds the errors of the bots that ran the spec for duration. It
+// can be called by several specs at the same time
+func (r *Report) AddSpec(spec *models.Spec, duration time.Duration, errs []error) {
+	s := &SpecReport{
+		Name:       spec.Name,
+		Duration:   duration.Seconds(),
+		Operations: make([]*OperationReport, len(spec.SequentialOperations)),
+		Failures:   []*Failure{},
+		Errors:     map[string]int{},
+	}
+	for idx, op := range spec.SequentialOperations {
+		s.Operations[idx] = &OperationReport{Step: idx, Type: op.Type, URI: op.URI, Failures: []*Failure{}}
+	}
+
+	for _, err := range errs {
+		if opErr, ok := err.(*bot.OperationError); ok {
+			s.Errors[failureGroup(opErr.Err)]++
+		} else {
+			s.Errors[failureGroup(err)]++
+		}
+		if opErr, ok := err.(*bot.OperationError); ok && opErr.Step < len(s.Operations) {
+			s.Operations[opErr.Step].Failures = addFailure(s.Operations[opErr.Step].Failures, opErr.Err)
+		} else {
+			s.Failures = addFailure(s.Failures, err)
+		}
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.Specs = append(r.Specs, s)
+	for msg, count := range s.Errors {
+		r.Errors[msg] += count
+	}
+}
+
+// Finish sets the stats of the run once all specs were added
+func (r *Report) Finish(summary *metrics.Summary, results []*metrics.ThresholdResult, interrupted bool) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.Duration = summary.Duration
+	r.Routes = summary.Routes
+	r.Interrupted = interrupted
+	r.Thresholds = make([]*ThresholdReport, len(results))
+	for idx, result := range results {
+		r.Thresholds[idx] = &ThresholdReport{
+			Threshold: result.Threshold.String(),
+			Value:     result.Value,
+			NoData:    result.NoData,
+			Passed:    result.Passed,
+		}
+	}
+	sort.Slice(r.Specs, func(i, j int) bool { return r.Specs[i].Name < r.Specs[j].Name })
+}
+
+// Passed returns true if no bot failed and every threshold passed
+func (r *Report) Passed() bool {
+	if len(r.Errors) > 0 {
+		return false
+	}
+	for _, t := range r.Thresholds {
+		if !t.Passed {
+			return false
+		}
+	}
+	return true
+}
+
+// Tests returns the number of operations reported in all specs
+func (r *Report) Tests() int {
+	tests := 0
+	for _, s := range r.Specs {
+		tests += len(s.Operations)
+	}
+	return tests
+}
+
+// Failed returns true if any bot failed running the operation
+func (o *OperationReport) Failed() bool {
+	return len(o.Failures) > 0
+}
+
+// Name describes the operation, such as [0] request connector.login
+func (o *OperationReport) Name() string {
+	return fmt.Sprintf("[%d] %s %s", o.Step, o.Type, o.URI)
+}
+
+// addFailure counts err in the failure of the same group, adding one if
+// there is none
+func addFailure(failures []*Failure, err error) []*Failure {
+	group := failureGroup(err)
+	for _, f := range failures {
+		if f.Group == group {
+			f.Count++
+			return failures
+		}
+	}
+
+	return append(failures, &Failure{
+		Kind:    failureKind(err),
+		Group:   group,
+		Message: failureMessage(err),
+		Body:    err.Error(),
+		Count:   1,
+	})
+}
+
+func failureKind(err error) string {
+	switch err.(type) {
+	case *bot.ExpectError:
+		return "ExpectError"
+	case *bot.TimeoutError:
+		return "TimeoutError"
+	default:
+		return "Error"
+	}
+}
+
+// failureGroup returns what identifies err among the failures of an
+// operation. Expectation errors are grouped by the expression and operator
+// that failed, leaving out the values received
+func failureGroup(err error) string {
+	expectErr, ok := err.(*bot.ExpectError)
+	if !ok {
+		return err.Error()
+	}
+	if expectationErr, ok := expectErr.Err.(*bot.ExpectationError); ok {
+		return fmt.Sprintf("%s %s failed", expectationErr.Expr, expectationErr.Operator)
+	}
+	return expectErr.Err.Error()
+}
+
+// failureMessage returns the short description of err. Expectation errors
+// keep the response data and the expectations for the body
+func failureMessage(err error) string {
+	if expectErr, ok := err.(*bot.ExpectError); ok {
+		return expectErr.Err.Error()
+	}
+	return err.Error()
+}
+
+// Writer writes the report in a format
+type Writer interface {
+	Write(r *Report, w io.Writer) error
+}
+
+// Writers are the formats reports can be written in
+var Writers = map[string]Writer{
+	"json":  &JSONWriter{},
+	"junit": &JUnitWriter{},
+	"html":  &HTMLWriter{},
+}
+
+// Output is a file a report is written to
+type Output struct {
+	Format string `mapstructure:"format"`
+	Path   string `mapstructure:"path"`
+}
+
+// Validate returns an error if the output format is unknown or it has no path
+func (o *Output) Validate() error {
+	if _, ok := Writers[o.Format]; !ok {
+		return fmt.Errorf("unknown report format %s", o.Format)
+	}
+	if o.Path == "" {
+		return fmt.Errorf("%s report has no path", o.Format)
+	}
+	return nil
+}
+
+// WriteFile writes the report to the output file
+func (r *Report) WriteFile(output *Output) error {
+	f, err := os.Create(output.Path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return Writers[output.Format].Write(r, f)
+}
@@ -0,0 +1,147 @@
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/topfreegames/pitaya-bot/bot"
+	"github.com/topfreegames/pitaya-bot/metrics"
+	"github.com/topfreegames/pitaya-bot/models"
+)
+
+func newTestReport() *Report {
+	spec := &models.Spec{Name: "specs/login.json", SequentialOperations: []*models.Operation{
+		{Type: "request", URI: "connector.login"},
+		{Type: "listen", URI: "room.start"},
+	}}
+	expect := models.ExpectSpec{"$response.code": {Type: "string", Value: "200"}}
+	// bots failing the same expectation with different responses
+	expectErr := bot.NewExpectError(&bot.ExpectationError{
+		Expr: "$response.code", Operator: "eq", Err: errors.New("$response.code: 500 eq 200 failed"),
+	}, []byte(`{"code":"500"}`), expect)
+	otherExpectErr := bot.NewExpectError(&bot.ExpectationError{
+		Expr: "$response.code", Operator: "eq", Err: errors.New("$response.code: 503 eq 200 failed"),
+	}, []byte(`{"code":"503"}`), expect)
+
+	r := NewReport(time.Unix(0, 0))
+	r.AddSpec(spec, 2*time.Second, []error{
+		&bot.OperationError{Step: 0, Type: "request", URI: "connector.login", Err: expectErr},
+		&bot.OperationError{Step: 0, Type: "request", URI: "connector.login", Err: otherExpectErr},
+		&bot.OperationError{Step: 0, Type: "request", URI: "connector.login", Err: &bot.TimeoutError{Route: "connector.login"}},
+		errors.New("connection refused"),
+	})
+
+	max := 100.0
+	threshold := &metrics.Threshold{Route: "connector.login", Metric: "p95", Max: &max}
+	summary := &metrics.Summary{Duration: 3, Routes: []*metrics.RouteStats{
+		{Kind: metrics.SummaryKindRequest, Route: "connector.login", Count: 10, Errors: 1, Min: 10, P50: 50, P95: 150, Max: 200},
+	}}
+	r.Finish(summary, []*metrics.ThresholdResult{threshold.Check(summary)}, false)
+	return r
+}
+
+func TestReportAddSpec(t *testing.T) {
+	r := newTestReport()
+	assert.False(t, r.Passed())
+	assert.Equal(t, 2, r.Tests())
+	assert.Equal(t, 3.0, r.Duration)
+	assert.Len(t, r.Errors, 3)
+	assert.Equal(t, 2, r.Errors["$response.code eq failed"])
+	assert.Equal(t, 1, r.Errors["connection refused"])
+	assert.Equal(t, 1, r.Errors["Timeout waiting for response on route connector.login"])
+
+	s := r.Specs[0]
+	assert.Equal(t, 2.0, s.Duration)
+	assert.Equal(t, []*Failure{
+		{Kind: "ExpectError", Group: "$response.code eq failed", Message: "$response.code: 500 eq 200 failed", Body: r.Specs[0].Operations[0].Failures[0].Body, Count: 2},
+		{Kind: "TimeoutError", Group: "Timeout waiting for response on route connector.login", Message: "Timeout waiting for response on route connector.login", Body: "Timeout waiting for response on route connector.login", Count: 1},
+	}, s.Operations[0].Failures)
+	assert.Contains(t, s.Operations[0].Failures[0].Body, `RawData: {"code":"500"}`)
+	assert.False(t, s.Operations[1].Failed())
+	assert.Equal(t, []*Failure{{Kind: "Error", Group: "connection refused", Message: "connection refused", Body: "connection refused", Count: 1}}, s.Failures)
+
+	assert.Equal(t, []*ThresholdReport{{Threshold: "request connector.login p95 <= 100", Value: 150}}, r.Thresholds)
+}
+
+func TestReportPassed(t *testing.T) {
+	r := NewReport(time.Now())
+	r.AddSpec(&models.Spec{Name: "specs/login.json"}, time.Second, nil)
+	r.Finish(&metrics.Summary{}, nil, false)
+	assert.True(t, r.Passed())
+}
+
+func TestOutputValidate(t *testing.T) {
+	var validateTable = map[string]struct {
+		output *Output
+		err    error
+	}{
+		"valid":          {&Output{Format: "junit", Path: "junit.xml"}, nil},
+		"unknown_format": {&Output{Format: "pdf", Path: "report.pdf"}, errors.New("unknown report format pdf")},
+		"no_path":        {&Output{Format: "html"}, errors.New("html report has no path")},
+	}
+
+	for name, table := range validateTable {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, table.err, table.output.Validate())
+		})
+	}
+}
+
+func TestJSONWriter(t *testing.T) {
+	var buf bytes.Buffer
+	assert.NoError(t, (&JSONWriter{}).Write(newTestReport(), &buf))
+
+	var decoded map[string]interface{}
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &decoded))
+	assert.Len(t, decoded["specs"], 1)
+	assert.Len(t, decoded["routes"], 1)
+	assert.Len(t, decoded["thresholds"], 1)
+	assert.Len(t, decoded["errors"], 3)
+}
+
+func TestJUnitWriter(t *testing.T) {
+	var buf bytes.Buffer
+	assert.NoError(t, (&JUnitWriter{}).Write(newTestReport(), &buf))
+	assert.True(t, strings.HasPrefix(buf.String(), xml.Header))
+
+	var suites junitTestSuites
+	assert.NoError(t, xml.Unmarshal(buf.Bytes(), &suites))
+	assert.Equal(t, 4, suites.Tests)
+	assert.Equal(t, 3, suites.Failures)
+	assert.Len(t, suites.Suites, 2)
+
+	spec := suites.Suites[0]
+	assert.Equal(t, "specs/login.json", spec.Name)
+	assert.Equal(t, []string{"[0] request connector.login", "[1] listen room.start", "bot"}, []string{
+		spec.TestCases[0].Name, spec.TestCases[1].Name, spec.TestCases[2].Name,
+	})
+	failure := spec.TestCases[0].Failure
+	assert.Equal(t, "3 bots failed: $response.code: 500 eq 200 failed", failure.Message)
+	assert.Equal(t, "ExpectError", failure.Type)
+	assert.Contains(t, failure.Body, `Expected: {"$response.code":{"type":"string","value":"200"}}`)
+	assert.Contains(t, failure.Body, "1 bots also failed with: Timeout waiting for response on route connector.login")
+	assert.Nil(t, spec.TestCases[1].Failure)
+
+	thresholds := suites.Suites[1]
+	assert.Equal(t, "threshold crossed with 150", thresholds.TestCases[0].Failure.Message)
+}
+
+func TestHTMLWriter(t *testing.T) {
+	var buf bytes.Buffer
+	assert.NoError(t, (&HTMLWriter{}).Write(newTestReport(), &buf))
+
+	html := buf.String()
+	assert.Contains(t, html, "<title>pitaya-bot report</title>")
+	assert.Contains(t, html, `<span class="failed">Failed</span>`)
+	assert.Contains(t, html, "request connector.login p95 &lt;= 100")
+	// the max latency fills the chart
+	assert.Contains(t, html, `width="400.0"`)
+	assert.Contains(t, html, "RawData: {&#34;code&#34;:&#34;500&#34;}")
+	assert.NotContains(t, html, "<script")
+}
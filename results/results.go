@@ -0,0 +1,203 @@
+package results
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Kinds of samples
+const (
+	KindRequest = "request"
+	KindPush    = "push"
+	KindConnect = "connect"
+)
+
+// Outcomes of samples
+const (
+	OutcomeOK           = "ok"
+	OutcomeError        = "error"
+	OutcomeTimeout      = "timeout"
+	OutcomeExpectFailed = "expect_failed"
+)
+
+// Formats of the results file
+const (
+	FormatCSV   = "csv"
+	FormatJSONL = "jsonl"
+)
+
+// Sample is a single request, push or connection of a bot
+type Sample struct {
+	Time  time.Time `json:"timestamp"`
+	BotID int       `json:"botId"`
+	Spec  string    `json:"spec"`
+	Kind  string    `json:"kind"`
+	Route string    `json:"route"`
+	// Latency is in milliseconds
+	Latency float64 `json:"latency"`
+	Outcome string  `json:"outcome"`
+	// Bytes is the size of the response or push received
+	Bytes int `json:"bytes"`
+}
+
+// Sink receives the samples of every bot
+type Sink interface {
+	Write(sample *Sample)
+}
+
+// csvHeader are the columns of the CSV results file
+var csvHeader = []string{"timestamp", "bot_id", "spec", "kind", "route", "latency_ms", "outcome", "bytes"}
+
+// encoder writes samples in a format
+type encoder interface {
+	encode(sample *Sample) error
+	flush() error
+}
+
+type csvEncoder struct {
+	writer *csv.Writer
+}
+
+func (e *csvEncoder) encode(sample *Sample) error {
+	return e.writer.Write([]string{
+		sample.Time.Format(time.RFC3339Nano),
+		strconv.Itoa(sample.BotID),
+		sample.Spec,
+		sample.Kind,
+		sample.Route,
+		strconv.FormatFloat(sample.Latency, 'f', 3, 64),
+		sample.Outcome,
+		strconv.Itoa(sample.Bytes),
+	})
+}
+
+func (e *csvEncoder) flush() error {
+	e.writer.Flush()
+	return e.writer.Error()
+}
+
+type jsonlEncoder struct {
+	encoder *json.Encoder
+}
+
+func (e *jsonlEncoder) encode(sample *Sample) error {
+	return e.encoder.Encode(sample)
+}
+
+func (e *jsonlEncoder) flush() error {
+	return nil
+}
+
+func newEncoder(format string, w io.Writer) (encoder, error) {
+	switch format {
+	case FormatCSV:
+		writer := csv.NewWriter(w)
+		if err := writer.Write(csvHeader); err != nil {
+			return nil, err
+		}
+		return &csvEncoder{writer: writer}, nil
+	case FormatJSONL:
+		return &jsonlEncoder{encoder: json.NewEncoder(w)}, nil
+	}
+
+	return nil, fmt.Errorf("unknown results format %s", format)
+}
+
+// FileSink writes the samples to a file in the background, so bots don't
+// wait for the disk. Samples are buffered up to bufferSize, once the buffer
+// is full bots wait for it to be written
+type FileSink struct {
+	file    *os.File
+	writer  *bufio.Writer
+	encoder encoder
+	samples chan *Sample
+	done    chan struct{}
+	mutex   sync.RWMutex
+	closed  bool
+	once    sync.Once
+	err     error
+}
+
+// NewFileSink creates the file at path and starts writing the samples to it
+func NewFileSink(path, format string, bufferSize int) (*FileSink, error) {
+	if bufferSize <= 0 {
+		return nil, fmt.Errorf("invalid results buffer size %d", bufferSize)
+	}
+	if format != FormatCSV && format != FormatJSONL {
+		return nil, fmt.Errorf("unknown results format %s", format)
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	writer := bufio.NewWriter(file)
+	enc, err := newEncoder(format, writer)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	s := &FileSink{
+		file:    file,
+		writer:  writer,
+		encoder: enc,
+		samples: make(chan *Sample, bufferSize),
+		done:    make(chan struct{}),
+	}
+	go s.run()
+	return s, nil
+}
+
+func (s *FileSink) run() {
+	defer close(s.done)
+	for sample := range s.samples {
+		if s.err != nil {
+			// keep draining so bots don't block
+			continue
+		}
+		s.err = s.encoder.encode(sample)
+	}
+}
+
+// Write queues the sample to be written. Samples written after Close, by
+// bots that outlive the run, are dropped
+//  - implements the Write method of the Sink interface
+func (s *FileSink) Write(sample *Sample) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	if s.closed {
+		return
+	}
+	s.samples <- sample
+}
+
+// Close writes the samples still queued and closes the file. It returns the
+// first error writing the file
+func (s *FileSink) Close() error {
+	s.once.Do(func() {
+		s.mutex.Lock()
+		s.closed = true
+		close(s.samples)
+		s.mutex.Unlock()
+		<-s.done
+
+		if s.err == nil {
+			s.err = s.encoder.flush()
+		}
+		if err := s.writer.Flush(); s.err == nil {
+			s.err = err
+		}
+		if err := s.file.Close(); s.err == nil {
+			s.err = err
+		}
+	})
+	return s.err
+}
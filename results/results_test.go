@@ -0,0 +1,117 @@
+package results
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewFileSink(t *testing.T) {
+	dir, err := ioutil.TempDir("", "results")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	var newFileSinkTable = map[string]struct {
+		path       string
+		format     string
+		bufferSize int
+		err        error
+	}{
+		"csv":            {filepath.Join(dir, "results.csv"), FormatCSV, 10, nil},
+		"jsonl":          {filepath.Join(dir, "results.jsonl"), FormatJSONL, 10, nil},
+		"invalid_format": {filepath.Join(dir, "results.xml"), "xml", 10, errors.New("unknown results format xml")},
+		"invalid_buffer": {filepath.Join(dir, "results.csv"), FormatCSV, 0, errors.New("invalid results buffer size 0")},
+	}
+
+	for name, table := range newFileSinkTable {
+		t.Run(name, func(t *testing.T) {
+			sink, err := NewFileSink(table.path, table.format, table.bufferSize)
+			assert.Equal(t, table.err, err)
+			if sink != nil {
+				assert.NoError(t, sink.Close())
+			}
+		})
+	}
+}
+
+func writeSamples(t *testing.T, format string) []string {
+	dir, err := ioutil.TempDir("", "results")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "results."+format)
+	sink, err := NewFileSink(path, format, 1)
+	assert.NoError(t, err)
+
+	// bots write concurrently, waiting on the small buffer
+	var wg sync.WaitGroup
+	for id := 0; id < 10; id++ {
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+			sink.Write(&Sample{
+				Time:    time.Date(2019, 1, 2, 3, 4, 5, 0, time.UTC),
+				BotID:   id,
+				Spec:    "specs/login.json",
+				Kind:    KindRequest,
+				Route:   "connector.login",
+				Latency: 12.5,
+				Outcome: OutcomeOK,
+				Bytes:   42,
+			})
+		}(id)
+	}
+	wg.Wait()
+	assert.NoError(t, sink.Close())
+	assert.NoError(t, sink.Close())
+
+	raw, err := ioutil.ReadFile(path)
+	assert.NoError(t, err)
+	return strings.Split(strings.TrimSpace(string(raw)), "\n")
+}
+
+func TestFileSinkCSV(t *testing.T) {
+	lines := writeSamples(t, FormatCSV)
+	assert.Len(t, lines, 11)
+	assert.Equal(t, "timestamp,bot_id,spec,kind,route,latency_ms,outcome,bytes", lines[0])
+	assert.Regexp(t, `^2019-01-02T03:04:05Z,\d,specs/login.json,request,connector.login,12.500,ok,42$`, lines[1])
+}
+
+func TestFileSinkJSONL(t *testing.T) {
+	lines := writeSamples(t, FormatJSONL)
+	assert.Len(t, lines, 10)
+
+	var sample Sample
+	assert.NoError(t, json.Unmarshal([]byte(lines[0]), &sample))
+	assert.Equal(t, "connector.login", sample.Route)
+	assert.Equal(t, 12.5, sample.Latency)
+	assert.Equal(t, 42, sample.Bytes)
+	assert.Contains(t, lines[0], `"timestamp":"2019-01-02T03:04:05Z"`)
+}
+
+func TestFileSinkWriteAfterClose(t *testing.T) {
+	dir, err := ioutil.TempDir("", "results")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "results.jsonl")
+	sink, err := NewFileSink(path, FormatJSONL, 1)
+	assert.NoError(t, err)
+	sink.Write(&Sample{Route: "connector.login"})
+	assert.NoError(t, sink.Close())
+
+	// a bot outliving the run must not panic
+	assert.NotPanics(t, func() { sink.Write(&Sample{Route: "connector.logout"}) })
+
+	raw, err := ioutil.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, strings.Count(string(raw), "\n"))
+}
@@ -2,16 +2,20 @@ package runner
 
 import (
 	"errors"
+	"fmt"
 	"runtime/debug"
 
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
 	pbot "github.com/topfreegames/pitaya-bot/bot"
+	"github.com/topfreegames/pitaya-bot/constants"
+	"github.com/topfreegames/pitaya-bot/metrics"
 	"github.com/topfreegames/pitaya-bot/models"
 	"github.com/topfreegames/pitaya-bot/state"
 )
 
-// Run runs a bot according to the spec
+// Run runs a bot according to the spec, reporting the iteration and the
+// bots running the spec
 func Run(
 	app *state.App,
 	config *viper.Viper,
@@ -19,19 +23,42 @@ func Run(
 	id int,
 	log logrus.FieldLogger,
 ) error {
+	tags := map[string]string{"spec": spec.Name}
+	metrics.ReportCount(app.MetricsReporter, constants.IterationStartedCount, tags, 1, log)
+	metrics.ReportGauge(app.MetricsReporter, constants.ActiveBots, tags, float64(app.AddActiveBots(spec.Name, 1)), log)
+
+	err := runBot(app, config, spec, id, log)
+
+	metrics.ReportGauge(app.MetricsReporter, constants.ActiveBots, tags, float64(app.AddActiveBots(spec.Name, -1)), log)
+	if err != nil {
+		metrics.ReportCount(app.MetricsReporter, constants.IterationFailedCount, tags, 1, log)
+	} else {
+		metrics.ReportCount(app.MetricsReporter, constants.IterationCompletedCount, tags, 1, log)
+	}
+
+	return err
+}
+
+func runBot(
+	app *state.App,
+	config *viper.Viper,
+	spec *models.Spec,
+	id int,
+	log logrus.FieldLogger,
+) (err error) {
 	logger := log.WithFields(logrus.Fields{
 		"source":   "pitaya-bot",
 		"function": "run",
 		"botId":    id,
 	})
 
-	var err error
 	defer func() {
-		err := recover()
-		if err != nil {
+		if rec := recover(); rec != nil {
 			logger.Error("PANIC")
 			logger.Errorf("%s", debug.Stack())
-			logger.Error(err)
+			logger.Error(rec)
+			// a bot that panicked failed its iteration
+			err = fmt.Errorf("panic: %v", rec)
 		}
 	}()
 
@@ -40,13 +67,13 @@ func Run(
 	if spec.SequentialOperations != nil {
 		logger.Debug("Found sequential operations")
 		for idx, step := range spec.SequentialOperations {
-			if err := step.Validate(); err != nil {
-				logger.WithError(err).Errorf("invalid step=[%d]", idx)
-				return err
+			if verr := step.Validate(); verr != nil {
+				logger.WithError(verr).Errorf("invalid step=[%d]", idx)
+				return verr
 			}
 		}
 
-		bot, err = pbot.NewSequentialBot(config, spec, id, app.MetricsReporter, logger)
+		bot, err = pbot.NewSequentialBot(config, spec, id, app.MetricsReporter, app.Results, app.Aborted, logger)
 		if err != nil {
 			logger.WithError(err).Error("Failed to create bot")
 			return err
@@ -54,7 +81,7 @@ func Run(
 	}
 
 	if bot == nil {
-		err := errors.New("No bot types defined")
+		err = errors.New("No bot types defined")
 		logger.Error(err)
 		return err
 	}
@@ -66,6 +93,14 @@ func Run(
 	}
 
 	err = bot.Run()
+	if err == constants.ErrBotAborted {
+		// the run was interrupted, still let the bot clean up after itself
+		logger.Warn("Bot aborted, finalizing")
+		if ferr := bot.Finalize(); ferr != nil {
+			logger.WithError(ferr).Error("Failed to finalize bot")
+		}
+		return err
+	}
 	if err != nil {
 		return err
 	}
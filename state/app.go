@@ -2,10 +2,22 @@ package state
 
 import (
 	"fmt"
+	"log"
 	"sync"
 
+	"github.com/google/uuid"
 	"github.com/spf13/viper"
 	"github.com/topfreegames/pitaya-bot/metrics"
+	"github.com/topfreegames/pitaya-bot/results"
+)
+
+// AbortReason is why the bots still running were aborted
+type AbortReason string
+
+// Reasons to abort a run
+const (
+	AbortReasonSignal    AbortReason = "signal"
+	AbortReasonThreshold AbortReason = "threshold"
 )
 
 // App is the struct that holds the app global data shared between packages
@@ -15,6 +27,35 @@ type App struct {
 	DieChan           chan struct{}
 	MetricsReporter   []metrics.Reporter
 	Mu                sync.Mutex
+	// Summary keeps the stats of each route, it is also one of the
+	// metrics reporters
+	Summary *metrics.SummaryReporter
+	// WaitScrape is true if metrics are pulled by a reporter, so the run
+	// must wait for them to be collected before it ends
+	WaitScrape bool
+	// Pusher sends the prometheus metrics to a Pushgateway, it is nil if
+	// they are scraped instead
+	Pusher *metrics.PrometheusPusher
+	// Results receives the samples of every bot, it is nil if they are not
+	// written anywhere
+	Results results.Sink
+
+	// Interrupted is closed when the run is interrupted by a signal
+	Interrupted chan struct{}
+	// Stopped is closed when no new bots must be started, because the run
+	// was interrupted or aborted
+	Stopped chan struct{}
+	// Aborted is closed when the bots still running must stop, after the
+	// grace period of an interruption or when a threshold is crossed
+	Aborted chan struct{}
+
+	interruptOnce sync.Once
+	stopOnce      sync.Once
+	abortOnce     sync.Once
+	abortReason   AbortReason
+
+	activeBotsMutex sync.Mutex
+	activeBots      map[string]int
 }
 
 // NewApp is the NewApp constructor
@@ -22,24 +63,153 @@ func NewApp(config *viper.Viper, shouldReportMetrics bool) *App {
 	app := &App{
 		FinishedExecution: false,
 		DieChan:           make(chan struct{}),
+		Summary:           metrics.NewSummaryReporter(),
+		Interrupted:       make(chan struct{}),
+		Stopped:           make(chan struct{}),
+		Aborted:           make(chan struct{}),
 	}
 
+	app.MetricsReporter = []metrics.Reporter{app.Summary}
 	if shouldReportMetrics {
 		fmt.Println("[INFO] Will report metrics")
-		app.MetricsReporter = []metrics.Reporter{
-			metrics.GetPrometheusReporter(config.GetString("game"),
-				config.GetInt("prometheus.port"),
-				map[string]string{},
-				func() {
-					defer app.Mu.Unlock()
-					app.Mu.Lock()
-					if app.FinishedExecution && !app.ChannelClosed {
-						app.ChannelClosed = true
-						close(app.DieChan)
-					}
-				},
-			)}
+		reporters := config.GetStringSlice("metrics.reporters")
+		if len(reporters) == 0 {
+			reporters = []string{"prometheus"}
+		}
+		for _, name := range reporters {
+			app.MetricsReporter = append(app.MetricsReporter, app.newReporter(config, name))
+		}
 	}
 
 	return app
 }
+
+// newReporter creates the metrics reporter with the given name, exiting if
+// it is unknown or can't be created
+func (a *App) newReporter(config *viper.Viper, name string) metrics.Reporter {
+	switch name {
+	case "prometheus":
+		if url := config.GetString("prometheus.pushgateway.url"); url != "" {
+			runID := config.GetString("prometheus.pushgateway.runId")
+			if runID == "" {
+				runID = uuid.New().String()
+			}
+			fmt.Printf("[INFO] Will push metrics to %s with run id %s\n", url, runID)
+			a.Pusher = metrics.NewPrometheusPusher(url, config.GetString("prometheus.pushgateway.job"), runID)
+			return metrics.GetPrometheusPushReporter(config.GetString("game"), map[string]string{}, histogramBuckets(config))
+		}
+
+		// the run waits for prometheus to scrape the last values
+		a.WaitScrape = true
+		return metrics.GetPrometheusReporter(config.GetString("game"),
+			config.GetInt("prometheus.port"),
+			map[string]string{},
+			histogramBuckets(config),
+			func() {
+				defer a.Mu.Unlock()
+				a.Mu.Lock()
+				if a.FinishedExecution && !a.ChannelClosed {
+					a.ChannelClosed = true
+					close(a.DieChan)
+				}
+			},
+		)
+	case "statsd":
+		reporter, err := metrics.NewStatsdReporter(
+			config.GetString("metrics.statsd.host"),
+			config.GetString("metrics.statsd.prefix"),
+			config.GetBool("metrics.statsd.dogstatsd"),
+			map[string]string{"game": config.GetString("game"), "clientType": "pitaya-bot"},
+		)
+		if err != nil {
+			log.Fatalf("Failed to create statsd reporter: %s", err)
+		}
+		return reporter
+	}
+
+	log.Fatalf("Unknown metrics reporter %s", name)
+	return nil
+}
+
+// histogramBuckets returns the buckets of the prometheus histograms, exiting
+// if they are invalid
+func histogramBuckets(config *viper.Viper) []float64 {
+	var buckets []float64
+	if err := config.UnmarshalKey("prometheus.histogram.buckets", &buckets); err != nil {
+		log.Fatalf("Failed to read histogram buckets: %s", err)
+	}
+	if len(buckets) == 0 {
+		buckets = metrics.DefaultBuckets
+	}
+	if err := metrics.ValidateBuckets(buckets); err != nil {
+		log.Fatalf("Invalid histogram buckets: %s", err)
+	}
+	return buckets
+}
+
+// AddActiveBots adds delta to the number of bots running the spec and
+// returns the new number
+func (a *App) AddActiveBots(spec string, delta int) int {
+	a.activeBotsMutex.Lock()
+	defer a.activeBotsMutex.Unlock()
+	if a.activeBots == nil {
+		a.activeBots = make(map[string]int)
+	}
+	a.activeBots[spec] += delta
+	return a.activeBots[spec]
+}
+
+// Interrupt closes the Interrupted and Stopped channels, it can be called
+// more than once
+func (a *App) Interrupt() {
+	a.interruptOnce.Do(func() {
+		close(a.Interrupted)
+	})
+	a.stop()
+}
+
+func (a *App) stop() {
+	a.stopOnce.Do(func() {
+		close(a.Stopped)
+	})
+}
+
+// IsInterrupted returns true if the run was interrupted
+func (a *App) IsInterrupted() bool {
+	select {
+	case <-a.Interrupted:
+		return true
+	default:
+		return false
+	}
+}
+
+// IsStopped returns true if no new bots must be started
+func (a *App) IsStopped() bool {
+	select {
+	case <-a.Stopped:
+		return true
+	default:
+		return false
+	}
+}
+
+// Abort closes the Stopped and Aborted channels, it can be called more than
+// once and keeps the first reason. It doesn't mark the run as interrupted
+func (a *App) Abort(reason AbortReason) {
+	a.stop()
+	a.abortOnce.Do(func() {
+		a.abortReason = reason
+		close(a.Aborted)
+	})
+}
+
+// GetAbortReason returns why the run was aborted, it is empty if it wasn't
+func (a *App) GetAbortReason() AbortReason {
+	select {
+	case <-a.Aborted:
+		return a.abortReason
+	default:
+		return ""
+	}
+}
@@ -7,13 +7,24 @@ import (
 	"github.com/stretchr/testify/assert"
 )
 
+func newReportersConfig(reporters ...string) *viper.Viper {
+	config := viper.New()
+	config.Set("metrics.reporters", reporters)
+	config.Set("metrics.statsd.host", "localhost:8125")
+	return config
+}
+
 func TestNewApp(t *testing.T) {
 	var assertTypeTable = map[string]struct {
 		config              *viper.Viper
 		shouldReportMetrics bool
+		reporters           int
+		waitScrape          bool
 	}{
-		"without_report": {viper.New(), false},
-		"with_report":    {viper.New(), true},
+		"without_report": {viper.New(), false, 1, false},
+		"with_report":    {viper.New(), true, 2, true},
+		"statsd":         {newReportersConfig("statsd"), true, 2, false},
+		"both":           {newReportersConfig("prometheus", "statsd"), true, 3, true},
 	}
 
 	for name, table := range assertTypeTable {
@@ -22,11 +33,50 @@ func TestNewApp(t *testing.T) {
 			assert.Equal(t, false, app.ChannelClosed)
 			assert.Equal(t, false, app.FinishedExecution)
 			assert.Empty(t, app.DieChan)
-			if table.shouldReportMetrics {
-				assert.NotEmpty(t, app.MetricsReporter)
-			} else {
-				assert.Empty(t, app.MetricsReporter)
-			}
+			assert.NotNil(t, app.Summary)
+			assert.Contains(t, app.MetricsReporter, app.Summary)
+			assert.Len(t, app.MetricsReporter, table.reporters)
+			assert.Equal(t, table.waitScrape, app.WaitScrape)
 		})
 	}
 }
+
+func TestAppAddActiveBots(t *testing.T) {
+	app := NewApp(viper.New(), false)
+	assert.Equal(t, 1, app.AddActiveBots("a.json", 1))
+	assert.Equal(t, 2, app.AddActiveBots("a.json", 1))
+	assert.Equal(t, 1, app.AddActiveBots("b.json", 1))
+	assert.Equal(t, 1, app.AddActiveBots("a.json", -1))
+}
+
+func TestAppInterruptAndAbort(t *testing.T) {
+	app := NewApp(viper.New(), false)
+	assert.False(t, app.IsInterrupted())
+
+	app.Interrupt()
+	app.Interrupt()
+	assert.True(t, app.IsInterrupted())
+	assert.True(t, app.IsStopped())
+	select {
+	case <-app.Aborted:
+		t.Fatal("interrupt must not abort the bots")
+	default:
+	}
+
+	app.Abort(AbortReasonSignal)
+	app.Abort(AbortReasonThreshold)
+	<-app.Aborted
+	assert.Equal(t, AbortReasonSignal, app.GetAbortReason())
+}
+
+func TestAppAbortWithoutInterrupt(t *testing.T) {
+	app := NewApp(viper.New(), false)
+	assert.False(t, app.IsStopped())
+	assert.Equal(t, AbortReason(""), app.GetAbortReason())
+
+	app.Abort(AbortReasonThreshold)
+	<-app.Aborted
+	assert.True(t, app.IsStopped())
+	assert.False(t, app.IsInterrupted())
+	assert.Equal(t, AbortReasonThreshold, app.GetAbortReason())
+}
@@ -35,6 +35,22 @@ func (s *MemoryStorage) Set(key string, val interface{}) error {
 	return nil
 }
 
+// Delete removes the key
+func (s *MemoryStorage) Delete(key string) error {
+	i := map[string]interface{}(*s)
+	delete(i, key)
+	return nil
+}
+
+// Clone returns a copy of the storage, so changes to it don't affect the original
+func (s *MemoryStorage) Clone() Storage {
+	clone := make(map[string]interface{}, len(*s))
+	for k, v := range *s {
+		clone[k] = v
+	}
+	return NewMemoryStorage(clone)
+}
+
 func (s MemoryStorage) String() string {
 	j, err := json.Marshal(s)
 	if err != nil {
@@ -53,6 +53,18 @@ func TestMemoryStorageSet(t *testing.T) {
 	}
 }
 
+func TestMemoryStorageDelete(t *testing.T) {
+	t.Parallel()
+
+	store := &MemoryStorage{"attr": "ok", "attr2": true}
+	err := store.Delete("attr")
+	assert.NoError(t, err)
+	assert.Equal(t, &MemoryStorage{"attr2": true}, store)
+
+	err = store.Delete("attr")
+	assert.NoError(t, err)
+}
+
 func TestMemoryStorageNew(t *testing.T) {
 	t.Parallel()
 
@@ -97,3 +109,14 @@ func TestMemoryStorageString(t *testing.T) {
 		})
 	}
 }
+
+func TestMemoryStorageClone(t *testing.T) {
+	t.Parallel()
+
+	store := &MemoryStorage{"attr": "ok"}
+	clone := store.Clone()
+	clone.Set("attr", "changed")
+	clone.Set("attr2", true)
+	assert.Equal(t, &MemoryStorage{"attr": "ok"}, store)
+	assert.Equal(t, &MemoryStorage{"attr": "changed", "attr2": true}, clone)
+}
@@ -9,6 +9,8 @@ import (
 type Storage interface {
 	Get(key string) (interface{}, error)
 	Set(key string, value interface{}) error
+	Delete(key string) error
+	Clone() Storage
 	String() string
 }
 